@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Zhan1bek/BookStore/pkg/models"
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+)
+
+// Обработчик для создания автора
+func (app *application) createAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      string      `json:"name"`
+		Bio       string      `json:"bio"`
+		BirthDate models.Date `json:"birth_date"`
+		DeathDate models.Date `json:"death_date"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	author := &models.Author{
+		Name:      input.Name,
+		Bio:       input.Bio,
+		BirthDate: input.BirthDate,
+		DeathDate: input.DeathDate,
+	}
+
+	v := validator.New()
+	if models.ValidateAuthor(v, author); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Authors.Insert(author)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/authors/%d", author.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"author": author}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для получения автора по ID
+func (app *application) showAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	author, ok := app.readAuthorParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"author": author}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для получения списка авторов с поиском по имени
+func (app *application) listAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string
+		models.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Name = app.readStrings(qs, "name", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readStrings(qs, "sort", "name")
+	input.Filters.SortSafelist = []string{"id", "name", "birth_date", "-id", "-name", "-birth_date"}
+
+	if models.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	authors, metadata, err := app.models.Authors.GetAll(input.Name, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"authors": authors, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для частичного обновления автора
+func (app *application) updateAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	author, ok := app.readAuthorParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name      *string      `json:"name"`
+		Bio       *string      `json:"bio"`
+		BirthDate *models.Date `json:"birth_date"`
+		DeathDate *models.Date `json:"death_date"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		author.Name = *input.Name
+	}
+	if input.Bio != nil {
+		author.Bio = *input.Bio
+	}
+	if input.BirthDate != nil {
+		author.BirthDate = *input.BirthDate
+	}
+	if input.DeathDate != nil {
+		author.DeathDate = *input.DeathDate
+	}
+
+	v := validator.New()
+	if models.ValidateAuthor(v, author); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Authors.Update(author)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"author": author}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для удаления автора
+func (app *application) deleteAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Authors.Delete(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "author successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для получения книг автора с пагинацией и сортировкой
+func (app *application) listAuthorBooksHandler(w http.ResponseWriter, r *http.Request) {
+	author, ok := app.readAuthorParam(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters := models.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readStrings(qs, "sort", "id"),
+		SortSafelist: []string{"id", "title", "price", "avg_rating", "-id", "-title", "-price", "-avg_rating"},
+		After:        app.readStrings(qs, "after", ""),
+		Before:       app.readStrings(qs, "before", ""),
+		Scope:        app.listScope(r),
+	}
+	if models.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rate, ok := app.displayCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	books, metadata, err := app.models.Books.GetAll(models.BookQuery{AuthorIDs: []int64{author.ID}}, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if err := rate.ConvertBooks(books...); err != nil {
+		app.convertPricesErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Translations.LocalizeBooks(app.contentLocale(r), books...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"author": author, "books": books, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readAuthorParam загружает автора по ID из URL. Если автор не найден или произошла ошибка,
+// ответ уже отправлен и возвращается false.
+func (app *application) readAuthorParam(w http.ResponseWriter, r *http.Request) (*models.Author, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	author, err := app.models.Authors.Get(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return author, true
+}
+
+// resolveBookAuthors проверяет, что все авторы из списка существуют, и подставляет их имена.
+// Роль по умолчанию — "author". Неизвестные ID записываются в v.
+func (app *application) resolveBookAuthors(v *validator.Validator, authors []*models.BookAuthor) error {
+	if len(authors) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(authors))
+	for i, author := range authors {
+		ids[i] = author.ID
+	}
+
+	known, err := app.models.Authors.GetByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, author := range authors {
+		if author.Role == "" {
+			author.Role = "author"
+		}
+
+		found, ok := known[author.ID]
+		if !ok {
+			v.AddError("authors", fmt.Sprintf("unknown author id %d", author.ID))
+			continue
+		}
+		author.Name = found.Name
+	}
+	return nil
+}
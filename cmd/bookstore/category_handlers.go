@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Zhan1bek/BookStore/pkg/models"
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+)
+
+// Обработчик для получения всего дерева категорий
+func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	tree, err := app.models.Categories.Tree()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Translations.LocalizeCategoryTree(app.contentLocale(r), tree)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": tree}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик страницы категории: путь от корня, количество книг и подкатегории с количеством
+// книг в каждой. Сами книги выбираются через /api/v1/books/list?category=ID.
+func (app *application) showCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category, ok := app.readCategoryParam(w, r)
+	if !ok {
+		return
+	}
+
+	path, err := app.models.Categories.Path(category.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	count, err := app.models.Categories.BookCount(category.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	subcategories, err := app.models.Categories.Subcategories(category.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Названия самой категории, пути к ней и подкатегорий переводятся одним запросом
+	localized := append([]*models.Category{category}, path...)
+	for _, subcategory := range subcategories {
+		localized = append(localized, subcategory.Category)
+	}
+	err = app.models.Translations.LocalizeCategories(app.contentLocale(r), localized...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"category":      models.CategoryCount{Category: category, BookCount: count},
+		"path":          path,
+		"subcategories": subcategories,
+	}
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для создания категории
+func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ParentID int64  `json:"parent_id"`
+		Name     string `json:"name"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category := &models.Category{
+		ParentID: input.ParentID,
+		Name:     input.Name,
+	}
+
+	v := validator.New()
+	if models.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if !app.checkParentCategory(w, r, v, category.ParentID) {
+		return
+	}
+
+	err = app.models.Categories.Insert(category)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicateCategory):
+			v.AddError("name", "a category with this name already exists at this level")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/categories/%d", category.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"category": category}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для переименования категории или ее переноса под другого родителя.
+// parent_id, равный 0, переносит категорию на верхний уровень.
+func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category, ok := app.readCategoryParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ParentID *int64  `json:"parent_id"`
+		Name     *string `json:"name"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.ParentID != nil {
+		category.ParentID = *input.ParentID
+	}
+	if input.Name != nil {
+		category.Name = *input.Name
+	}
+
+	v := validator.New()
+	if models.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if !app.checkParentCategory(w, r, v, category.ParentID) {
+		return
+	}
+
+	err = app.models.Categories.Update(category)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrCategoryCycle):
+			v.AddError("parent_id", "must not be the category itself or one of its subcategories")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, models.ErrDuplicateCategory):
+			v.AddError("name", "a category with this name already exists at this level")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, models.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для удаления категории. Категорию с подкатегориями удалить нельзя.
+func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Categories.Delete(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, models.ErrCategoryNotEmpty):
+			app.errorResponse(w, r, http.StatusConflict, "the category has subcategories, move or delete them first")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "category successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readCategoryParam загружает категорию по ID из URL. Если категория не найдена или
+// произошла ошибка, ответ уже отправлен и возвращается false.
+func (app *application) readCategoryParam(w http.ResponseWriter, r *http.Request) (*models.Category, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	category, err := app.models.Categories.Get(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return category, true
+}
+
+// checkParentCategory проверяет, что родительская категория существует. Если нет, ответ
+// уже отправлен и возвращается false.
+func (app *application) checkParentCategory(w http.ResponseWriter, r *http.Request, v *validator.Validator, parentID int64) bool {
+	if parentID == 0 {
+		return true
+	}
+
+	_, err := app.models.Categories.Get(parentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			v.AddError("parent_id", "unknown category")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+	return true
+}
+
+// resolveBookCategories проверяет, что все категории из списка существуют, и подставляет их
+// названия. Неизвестные ID записываются в v.
+func (app *application) resolveBookCategories(v *validator.Validator, categories []*models.BookCategory) error {
+	if len(categories) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(categories))
+	for i, category := range categories {
+		ids[i] = category.ID
+	}
+
+	known, err := app.models.Categories.GetByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, category := range categories {
+		found, ok := known[category.ID]
+		if !ok {
+			v.AddError("categories", fmt.Sprintf("unknown category id %d", category.ID))
+			continue
+		}
+		category.Name = found.Name
+	}
+	return nil
+}
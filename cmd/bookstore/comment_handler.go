@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/Zhan1bek/BookStore/pkg/models"
+	"github.com/Zhan1bek/BookStore/pkg/validator"
 	"net/http"
 	"strconv"
 )
@@ -82,13 +83,29 @@ func (app *application) GetComments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comments, err := app.models.Comment.GetAllByBook(bookID)
+	v := validator.New()
+	qs := r.URL.Query()
+	filters := models.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readStrings(qs, "sort", "created_at"),
+		SortSafelist: []string{"id", "created_at", "-id", "-created_at"},
+		After:        app.readStrings(qs, "after", ""),
+		Before:       app.readStrings(qs, "before", ""),
+		Scope:        app.listScope(r),
+	}
+	if models.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	comments, metadata, err := app.models.Comment.GetAllByBook(bookID, filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"comments": comments}, nil)
+	app.writeJSON(w, http.StatusOK, envelope{"comments": comments, "metadata": metadata}, nil)
 }
 
 // DeleteComment удаляет комментарий, если пользователь является его автором.
@@ -13,6 +13,13 @@ type contextKey string
 // context.
 const userContextKey = contextKey("user")
 
+// impersonationContextKey is used as a key for the impersonation details of requests made
+// with an impersonation token.
+const impersonationContextKey = contextKey("impersonation")
+
+// localeContextKey is used as a key for the locale negotiated for the request.
+const localeContextKey = contextKey("locale")
+
 // contextSetUser returns a new copy of the request with the provided User struct added to the
 // context.
 func (app *application) contextSetUser(r *http.Request, user *models.User) *http.Request {
@@ -31,3 +38,54 @@ func (app *application) contextGetUser(r *http.Request) *models.User {
 
 	return user
 }
+
+// contextSetImpersonation returns a new copy of the request with the impersonation details added
+// to the context.
+func (app *application) contextSetImpersonation(r *http.Request, impersonation *models.Impersonation) *http.Request {
+	ctx := context.WithValue(r.Context(), impersonationContextKey, impersonation)
+	return r.WithContext(ctx)
+}
+
+// contextGetImpersonation retrieves the impersonation details from the request context. Unlike
+// contextGetUser it returns nil when there are none, since most requests are not impersonated.
+func (app *application) contextGetImpersonation(r *http.Request) *models.Impersonation {
+	impersonation, _ := r.Context().Value(impersonationContextKey).(*models.Impersonation)
+	return impersonation
+}
+
+// contextSetLocale returns a new copy of the request with the negotiated locale added to the
+// context.
+func (app *application) contextSetLocale(r *http.Request, locale string) *http.Request {
+	ctx := context.WithValue(r.Context(), localeContextKey, locale)
+	return r.WithContext(ctx)
+}
+
+// contextGetLocale retrieves the locale of the response from the request context. Requests
+// that did not pass through negotiateLocale get the default locale.
+func (app *application) contextGetLocale(r *http.Request) string {
+	locale, ok := r.Context().Value(localeContextKey).(string)
+	if !ok {
+		return app.config.defaultLocale
+	}
+	return locale
+}
+
+// contentLocale returns the locale that book and category texts should be translated into,
+// or "" when the response is in the default locale and the stored texts are used as they are.
+func (app *application) contentLocale(r *http.Request) string {
+	locale := app.contextGetLocale(r)
+	if locale == app.config.defaultLocale {
+		return ""
+	}
+	return locale
+}
+
+// actorID returns the ID of the user making the request, or 0 for anonymous requests. It is
+// recorded as the author of changes such as price updates.
+func (app *application) actorID(r *http.Request) int64 {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		return 0
+	}
+	return user.ID
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Zhan1bek/BookStore/pkg/models"
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+	"github.com/gorilla/mux"
+)
+
+// currencyRateInput — тело запроса PUT /api/v1/currencies/{currency} и элемент файла курсов.
+// Курс принимается числом или строкой и сохраняется без преобразования в float64.
+type currencyRateInput struct {
+	Currency     string        `json:"currency"`
+	Rate         json.Number   `json:"rate"`
+	RoundingStep *models.Money `json:"rounding_step"`
+	RoundingMode string        `json:"rounding_mode"`
+}
+
+// rate возвращает курс валюты currency. Шаг округления по умолчанию — 0.01, правило — до
+// ближайшего.
+func (input currencyRateInput) rate(currency string) *models.CurrencyRate {
+	rate := &models.CurrencyRate{
+		Currency:     strings.ToUpper(strings.TrimSpace(currency)),
+		Rate:         input.Rate.String(),
+		RoundingStep: models.NewMoney(1),
+		RoundingMode: input.RoundingMode,
+	}
+	if input.RoundingStep != nil {
+		rate.RoundingStep = *input.RoundingStep
+	}
+	rate.RoundingStep.Currency = rate.Currency
+	if rate.RoundingMode == "" {
+		rate.RoundingMode = "nearest"
+	}
+	return rate
+}
+
+// Обработчик для получения курсов всех валют
+func (app *application) listCurrencyRatesHandler(w http.ResponseWriter, r *http.Request) {
+	rates, err := app.models.Currencies.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"base_currency": models.BaseCurrency, "currencies": rates}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для получения курса валюты
+func (app *application) showCurrencyRateHandler(w http.ResponseWriter, r *http.Request) {
+	rate, err := app.models.Currencies.Get(strings.ToUpper(mux.Vars(r)["currency"]))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrUnknownCurrency):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"currency": rate}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для добавления или обновления курса валюты
+func (app *application) putCurrencyRateHandler(w http.ResponseWriter, r *http.Request) {
+	var input currencyRateInput
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Currency == "", "currency", "is taken from the URL")
+	rate := input.rate(mux.Vars(r)["currency"])
+	if models.ValidateCurrencyRate(v, rate); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Currencies.Upsert(rate, app.actorID(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"currency": rate}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для удаления курса валюты. Курс базовой валюты удалить нельзя.
+func (app *application) deleteCurrencyRateHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.models.Currencies.Delete(strings.ToUpper(mux.Vars(r)["currency"]))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrBaseCurrency):
+			app.failedValidationResponse(w, r, map[string]string{"currency": "the base currency cannot be deleted"})
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "currency successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// displayCurrency возвращает курс валюты, в которой нужно показать цены: из параметра
+// currency или из настроек пользователя. Nil означает базовую валюту. Если валюта не
+// поддерживается или произошла ошибка, ответ уже отправлен и возвращается false.
+func (app *application) displayCurrency(w http.ResponseWriter, r *http.Request) (*models.CurrencyRate, bool) {
+	currency := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("currency")))
+	if currency == "" {
+		currency = app.contextGetUser(r).Currency
+	}
+	if currency == "" || currency == models.BaseCurrency {
+		return nil, true
+	}
+
+	rate, err := app.models.Currencies.Get(currency)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrUnknownCurrency):
+			app.failedValidationResponse(w, r, map[string]string{"currency": "is not supported"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return rate, true
+}
+
+// convertPricesErrorResponse отправляет ответ на ошибку пересчета цен в валюту отображения.
+// Цены, которые не помещаются в допустимый диапазон сумм, — ошибка выбора валюты, а не сервера.
+func (app *application) convertPricesErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrAmountOutOfRange):
+		app.failedValidationResponse(w, r, map[string]string{"currency": "prices are too large to be shown in this currency"})
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// loadCurrencyRates загружает курсы валют из JSON-файла со списком объектов вида
+// {"currency": "KZT", "rate": "478.5", "rounding_step": "1", "rounding_mode": "nearest"}.
+// Курсы из файла заменяют сохраненные, остальные валюты не меняются.
+func (app *application) loadCurrencyRates(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var inputs []currencyRateInput
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&inputs); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i, input := range inputs {
+		rate := input.rate(input.Currency)
+		v := validator.New()
+		if models.ValidateCurrencyRate(v, rate); !v.Valid() {
+			return fmt.Errorf("%s: currency %d (%s): %v", path, i, rate.Currency, v.Errors)
+		}
+		if err := app.models.Currencies.Upsert(rate, 0); err != nil {
+			return err
+		}
+	}
+
+	app.logger.PrintInfo("loaded currency rates", map[string]string{
+		"file":  path,
+		"count": fmt.Sprintf("%d", len(inputs)),
+	})
+	return nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Zhan1bek/BookStore/pkg/models"
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+)
+
+// Обработчик для получения всех изданий книги
+func (app *application) listBookEditionsHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBookParam(w, r)
+	if !ok {
+		return
+	}
+
+	rate, ok := app.displayCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	editions := book.Editions
+	if editions == nil {
+		editions = []*models.Edition{}
+	}
+	if err := rate.ConvertEditions(editions...); err != nil {
+		app.convertPricesErrorResponse(w, r, err)
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"editions": editions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для добавления издания книги
+func (app *application) createEditionHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBookParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Format        string       `json:"format"`
+		ISBN          string       `json:"isbn"`
+		Price         models.Money `json:"price"`
+		StockQuantity int          `json:"stock_quantity"`
+		PageCount     int          `json:"page_count"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	edition := &models.Edition{
+		BookID:        book.ID,
+		Format:        input.Format,
+		ISBN:          input.ISBN,
+		Price:         input.Price,
+		StockQuantity: input.StockQuantity,
+		PageCount:     input.PageCount,
+	}
+
+	v := validator.New()
+	if models.ValidateEdition(v, edition); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Editions.Insert(edition, app.actorID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicateISBN):
+			v.AddError("isbn", "an edition with this ISBN already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/editions/%d", edition.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"edition": edition}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для получения издания по ID
+func (app *application) showEditionHandler(w http.ResponseWriter, r *http.Request) {
+	edition, ok := app.readEditionParam(w, r)
+	if !ok {
+		return
+	}
+
+	rate, ok := app.displayCurrency(w, r)
+	if !ok {
+		return
+	}
+	if err := rate.ConvertEditions(edition); err != nil {
+		app.convertPricesErrorResponse(w, r, err)
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"edition": edition}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для частичного обновления издания: цены, остатка, ISBN и т.д.
+func (app *application) updateEditionHandler(w http.ResponseWriter, r *http.Request) {
+	edition, ok := app.readEditionParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Format        *string       `json:"format"`
+		ISBN          *string       `json:"isbn"`
+		Price         *models.Money `json:"price"`
+		StockQuantity *int          `json:"stock_quantity"`
+		PageCount     *int          `json:"page_count"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Format != nil {
+		edition.Format = *input.Format
+	}
+	if input.ISBN != nil {
+		edition.ISBN = *input.ISBN
+	}
+	if input.Price != nil {
+		edition.Price = *input.Price
+	}
+	if input.StockQuantity != nil {
+		edition.StockQuantity = *input.StockQuantity
+	}
+	if input.PageCount != nil {
+		edition.PageCount = *input.PageCount
+	}
+
+	v := validator.New()
+	if models.ValidateEdition(v, edition); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Editions.Update(edition, app.actorID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicateISBN):
+			v.AddError("isbn", "an edition with this ISBN already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, models.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"edition": edition}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для удаления издания
+func (app *application) deleteEditionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Editions.Delete(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "edition successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readEditionParam загружает издание по ID из URL. Если издание не найдено или произошла
+// ошибка, ответ уже отправлен и возвращается false.
+func (app *application) readEditionParam(w http.ResponseWriter, r *http.Request) (*models.Edition, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	edition, err := app.models.Editions.Get(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return edition, true
+}
+
+// readBookParam загружает книгу по ID из URL. Если книга не найдена или произошла ошибка,
+// ответ уже отправлен и возвращается false.
+func (app *application) readBookParam(w http.ResponseWriter, r *http.Request) (*models.Book, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	book, err := app.models.Books.Get(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return book, true
+}
@@ -3,23 +3,35 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/i18n"
 )
 
 // logError method is a generic helper for logging an error message in *application, as well
 // as the requested method and request URL.
 func (app *application) logError(r *http.Request, err error) {
-	app.logger.PrintError(err, map[string]string{
+	properties := map[string]string{
 		"request_method": r.Method,
 		"request_url":    r.URL.String(),
-	})
+	}
+
+	// Tag errors in impersonated requests with the staff user behind them.
+	if impersonation := app.contextGetImpersonation(r); impersonation != nil {
+		properties["user_id"] = formatID(impersonation.TargetUserID)
+		properties["impersonated_by"] = formatID(impersonation.StaffUserID)
+	}
+
+	app.logger.PrintError(err, properties)
 }
 
 // errorResponse method is a generic helper for sending JSON-formatted error messages to the
 // client with a given status code. Note that we're using an interface{} type for the message
 // parameter, rather than just a string type, as this gives us more flexibility over the values
-// that we can include in the response.
+// that we can include in the response. Messages and validation errors are translated into the
+// locale negotiated for the request.
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message interface{}) {
-	env := envelope{"error": message}
+	env := envelope{"error": app.translate(r, message)}
 
 	// Write the response using the writeJSON() helper. If this happens to return an error
 	// then log it, and fall back to sending the client an empty response with a 500 Internal
@@ -31,6 +43,25 @@ func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, st
 	}
 }
 
+// translate returns a copy of an error message or a map of validation errors in the locale of
+// the request. Other values are returned unchanged.
+func (app *application) translate(r *http.Request, message interface{}) interface{} {
+	locale := app.contextGetLocale(r)
+
+	switch message := message.(type) {
+	case string:
+		return i18n.Translate(locale, message)
+	case map[string]string:
+		translated := make(map[string]string, len(message))
+		for key, value := range message {
+			translated[key] = i18n.Translate(locale, value)
+		}
+		return translated
+	default:
+		return message
+	}
+}
+
 // serverErrorResponse method is used when our application encounters an unexpected problem
 // at runtime. it logs the detailed error message, then uses the errorResponse() helper to send a
 // 500 Internal Server Error status code and JSON response (containing the generic error message)
@@ -110,3 +141,27 @@ func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Requ
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+// tooManyLoginAttemptsResponse sends a JSON-formatted error with a 429 Too Many Requests status
+// code and a Retry-After header to the client. The message deliberately does not say whether
+// the account exists.
+func (app *application) tooManyLoginAttemptsResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+
+	message := "too many failed login attempts, please try again later"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+// rateLimitExceededResponse sends a JSON-formatted error with a 429 Too Many Requests status
+// code to the client.
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+	message := "rate limit exceeded"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+// impersonationReadOnlyResponse sends a JSON-formatted error with a 403 Forbidden status code to
+// the client when a read-only impersonation token is used for a write request.
+func (app *application) impersonationReadOnlyResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this impersonation session is read-only"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
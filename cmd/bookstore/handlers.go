@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/Zhan1bek/BookStore/pkg/filterexpr"
 	"github.com/Zhan1bek/BookStore/pkg/models"
 	"github.com/Zhan1bek/BookStore/pkg/validator"
 	"github.com/gorilla/mux"
@@ -9,15 +11,17 @@ import (
 	"strconv"
 )
 
-func (app *application) respondWithError(w http.ResponseWriter, code int, message string) {
-	app.respondWithJSON(w, code, map[string]string{"error": message})
+// respondWithError sends an error message through errorResponse, so that it is translated to
+// the locale of the request like every other error of the API.
+func (app *application) respondWithError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	app.errorResponse(w, r, code, message)
 }
 
-func (app *application) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+func (app *application) respondWithJSON(w http.ResponseWriter, r *http.Request, code int, payload interface{}) {
 	response, err := json.Marshal(payload)
 
 	if err != nil {
-		app.respondWithError(w, http.StatusInternalServerError, "500 Internal Server Error")
+		app.respondWithError(w, r, http.StatusInternalServerError, "500 Internal Server Error")
 		return
 	}
 
@@ -28,42 +32,101 @@ func (app *application) respondWithJSON(w http.ResponseWriter, code int, payload
 
 func (app *application) createBookHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title         string  `json:"title"`
-		Author        string  `json:"author"`
-		Price         float64 `json:"price"`
-		StockQuantity int     `json:"stock_quantity"`
+		Title           string               `json:"title"`
+		Author          string               `json:"author"`
+		Price           models.Money         `json:"price"`
+		StockQuantity   int                  `json:"stock_quantity"`
+		ISBN            string               `json:"isbn"`
+		Publisher       string               `json:"publisher"`
+		PublicationDate models.Date          `json:"publication_date"`
+		Language        string               `json:"language"`
+		PageCount       int                  `json:"page_count"`
+		Description     string               `json:"description"`
+		Authors         []*models.BookAuthor `json:"authors"`
+		Categories      []int64              `json:"categories"`
+		Format          string               `json:"format"`
+		Editions        []*models.Edition    `json:"editions"`
 	}
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		app.respondWithError(w, r, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
 	book := &models.Book{
-		Title:         input.Title,
-		Author:        input.Author,
-		Price:         input.Price,
-		StockQuantity: input.StockQuantity,
+		Title:           input.Title,
+		Author:          input.Author,
+		Publisher:       input.Publisher,
+		PublicationDate: input.PublicationDate,
+		Language:        input.Language,
+		PageCount:       input.PageCount,
+		Description:     input.Description,
+		Authors:         input.Authors,
+		Categories:      bookCategories(input.Categories),
+		Editions:        input.Editions,
 	}
 
-	err = app.models.Books.Insert(book)
+	// Издания передаются списком editions. Без него книга получает одно издание из полей
+	// format, isbn, price и stock_quantity, как до появления изданий.
+	v := validator.New()
+	if len(book.Editions) == 0 {
+		if input.Format == "" {
+			input.Format = "paperback"
+		}
+		book.Editions = []*models.Edition{{
+			Format:        input.Format,
+			ISBN:          input.ISBN,
+			Price:         input.Price,
+			StockQuantity: input.StockQuantity,
+			PageCount:     input.PageCount,
+		}}
+	} else {
+		v.Check(input.Format == "" && input.ISBN == "" && input.Price.IsZero() && input.StockQuantity == 0, "editions",
+			"format, isbn, price and stock_quantity must be set on each edition when editions are given")
+	}
+
+	// Авторы указываются по ID; если строка author не передана, она составляется из их имен
+	err = app.resolveBookAuthors(v, book.Authors)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if book.Author == "" {
+		book.Author = models.AuthorDisplayName(book.Authors)
+	}
+	err = app.resolveBookCategories(v, book.Categories)
 	if err != nil {
-		app.respondWithError(w, http.StatusInternalServerError, "500 Internal Server Error")
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	app.respondWithJSON(w, http.StatusCreated, book)
+	// Валидация полей книги и изданий, включая контрольную цифру ISBN
+	if models.ValidateBook(v, book); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Books.Insert(book, app.actorID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicateISBN):
+			v.AddError("isbn", "an edition with this ISBN already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.respondWithError(w, r, http.StatusInternalServerError, "500 Internal Server Error")
+		}
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusCreated, book)
 }
 
 func (app *application) GetBookList(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title     string
-		Author    string
-		PriceFrom float64
-		PriceTo   float64
-		MinRating float64
+		models.BookQuery
 		models.Filters
+		Facets []string
 	}
 
 	v := validator.New()
@@ -72,17 +135,64 @@ func (app *application) GetBookList(w http.ResponseWriter, r *http.Request) {
 	// Извлечение параметров фильтрации из строки запроса
 	input.Title = app.readStrings(qs, "title", "")
 	input.Author = app.readStrings(qs, "author", "")
-	input.PriceFrom = app.readFloat(qs, "priceFrom", 0, v)
-	input.PriceTo = app.readFloat(qs, "priceTo", 0, v)
+	input.PriceFrom = app.readMoney(qs, "priceFrom", v)
+	input.PriceTo = app.readMoney(qs, "priceTo", v)
 	input.MinRating = app.readFloat(qs, "minRating", 0, v)
+	input.ISBN = app.readStrings(qs, "isbn", "")
+	input.Publisher = app.readStrings(qs, "publisher", "")
+	input.Language = app.readStrings(qs, "language", "")
+	input.PublishedFrom = app.readInt(qs, "yearFrom", 0, v)
+	input.PublishedTo = app.readInt(qs, "yearTo", 0, v)
+	input.AuthorIDs = app.readIDs(qs, "authorId", v)
+	input.CategoryIDs = app.readIDs(qs, "category", v)
+	input.Search = app.readStrings(qs, "q", "")
+
+	// Выражение filter= разбирается с проверкой полей и типов значений, ошибки разбора
+	// возвращаются клиенту как ошибки поля filter
+	if filter := app.readStrings(qs, "filter", ""); filter != "" {
+		expr, err := filterexpr.Parse(filter, models.BookFilterFields)
+		if err != nil {
+			v.AddError("filter", err.Error())
+		}
+		input.Filter = expr
+	}
+
+	// ISBN в базе данных хранится в виде ISBN-13, поэтому фильтр приводится к тому же виду
+	if input.ISBN != "" {
+		isbn, err := models.NormalizeISBN(input.ISBN)
+		if err != nil {
+			v.AddError("isbn", "must be a valid ISBN-10 or ISBN-13")
+		}
+		input.ISBN = isbn
+	}
 
 	// Получение параметров пагинации и сортировки
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readStrings(qs, "sort", "id")
+	input.Filters.After = app.readStrings(qs, "after", "")
+	input.Filters.Before = app.readStrings(qs, "before", "")
+	input.Filters.Scope = app.listScope(r)
+
+	// При полнотекстовом поиске по умолчанию книги упорядочены по релевантности
+	if input.Search != "" {
+		input.Filters.Sort = app.readStrings(qs, "sort", "-rank")
+	}
+	v.Check(len(input.Search) <= 200, "q", "must not be more than 200 bytes long")
+
+	// Фасеты считаются только по запросу, например facets=author,price
+	input.Facets = app.readCSV(qs, "facets", nil)
+	for _, facet := range input.Facets {
+		v.Check(validator.In(facet, models.Facets...), "facets", "unknown facet "+facet)
+	}
+	v.Check(validator.Unique(input.Facets), "facets", "must not contain duplicate values")
 
 	// Установка списка допустимых параметров для сортировки
-	input.Filters.SortSafelist = []string{"id", "title", "author", "price", "avg_rating", "-id", "-title", "-author", "-price", "-avg_rating"}
+	// Сортировка может состоять из нескольких ключей через запятую, например sort=-avg_rating,price
+	input.Filters.SortSafelist = []string{
+		"id", "title", "author", "price", "avg_rating", "stock_quantity", "page_count", "rank",
+		"-id", "-title", "-author", "-price", "-avg_rating", "-stock_quantity", "-page_count", "-rank",
+	}
 
 	// Валидация фильтров
 	if models.ValidateFilters(v, input.Filters); !v.Valid() {
@@ -90,15 +200,97 @@ func (app *application) GetBookList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Цены показываются в валюте из параметра currency или из настроек пользователя, а
+	// priceFrom и priceTo всегда задаются в базовой валюте
+	rate, ok := app.displayCurrency(w, r)
+	if !ok {
+		return
+	}
+
 	// Получение списка книг с учетом фильтров
-	books, metadata, err := app.models.Books.GetAll(input.Title, input.Author, input.PriceFrom, input.PriceTo, input.MinRating, input.Filters)
+	books, metadata, err := app.models.Books.GetAll(input.BookQuery, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if err := rate.ConvertBooks(books...); err != nil {
+		app.convertPricesErrorResponse(w, r, err)
+		return
+	}
+
+	// Названия, описания и категории показываются на языке из параметра lang или заголовка
+	// Accept-Language, если для них есть перевод
+	err = app.models.Translations.LocalizeBooks(app.contentLocale(r), books...)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	env := envelope{"books": books, "metadata": metadata}
+
+	if len(input.Facets) > 0 {
+		facets, err := app.models.Books.Facets(input.BookQuery, input.Facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		err = app.models.Translations.LocalizeCategoryFacet(app.contentLocale(r), facets[models.FacetCategory])
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		env["facets"] = facets
+	}
+
+	// Если по запросу ничего не найдено, предлагаем похожее название или имя автора
+	if len(books) == 0 {
+		text := input.Search
+		if text == "" {
+			text = input.Title
+		}
+		if text != "" {
+			suggestion, err := app.models.Books.DidYouMean(text)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			if suggestion != "" {
+				env["did_you_mean"] = suggestion
+			}
+		}
+	}
+
 	// Возврат списка книг и метаданных в ответе
-	err = app.writeJSON(w, http.StatusOK, envelope{"books": books, "metadata": metadata}, nil)
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик автодополнения: возвращает похожие названия книг и имена авторов
+func (app *application) suggestBooksHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	q := app.readStrings(qs, "q", "")
+	limit := app.readInt(qs, "limit", 10, v)
+
+	v.Check(q != "", "q", "must be provided")
+	v.Check(len(q) <= 100, "q", "must not be more than 100 bytes long")
+	v.Check(limit > 0, "limit", "must be greater than 0")
+	v.Check(limit <= 20, "limit", "must be a maximum of 20")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := app.models.Books.Suggest(q, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -109,17 +301,32 @@ func (app *application) getBookHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil || id < 1 {
-		app.respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		app.respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	rate, ok := app.displayCurrency(w, r)
+	if !ok {
 		return
 	}
 
 	book, err := app.models.Books.Get(int64(id))
 	if err != nil {
-		app.respondWithError(w, http.StatusNotFound, "Book not found")
+		app.respondWithError(w, r, http.StatusNotFound, "Book not found")
+		return
+	}
+	if err := rate.ConvertBooks(book); err != nil {
+		app.convertPricesErrorResponse(w, r, err)
 		return
 	}
 
-	app.respondWithJSON(w, http.StatusOK, book)
+	err = app.models.Translations.LocalizeBooks(app.contentLocale(r), book)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, book)
 }
 
 // Обработчик для обновления книги
@@ -127,44 +334,126 @@ func (app *application) updateBookHandler(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil || id < 1 {
-		app.respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		app.respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	book, err := app.models.Books.Get(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.respondWithError(w, r, http.StatusNotFound, "Book not found")
+		default:
+			app.respondWithError(w, r, http.StatusInternalServerError, "500 Internal Server Error")
+		}
 		return
 	}
 
 	var input struct {
-		Title         *string  `json:"title"`
-		Author        *string  `json:"author"`
-		Price         *float64 `json:"price"`
-		StockQuantity *int     `json:"stock_quantity"`
+		Title           *string               `json:"title"`
+		Author          *string               `json:"author"`
+		Price           *models.Money         `json:"price"`
+		StockQuantity   *int                  `json:"stock_quantity"`
+		ISBN            *string               `json:"isbn"`
+		Publisher       *string               `json:"publisher"`
+		PublicationDate *models.Date          `json:"publication_date"`
+		Language        *string               `json:"language"`
+		PageCount       *int                  `json:"page_count"`
+		Description     *string               `json:"description"`
+		Authors         *[]*models.BookAuthor `json:"authors"`
+		Categories      *[]int64              `json:"categories"`
 	}
 
 	err = app.readJSON(w, r, &input)
 	if err != nil {
-		app.respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		app.respondWithError(w, r, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
-	book := models.Book{ID: int64(id)}
+	// Обновляются только переданные поля, остальные остаются как были
 	if input.Title != nil {
 		book.Title = *input.Title
 	}
 	if input.Author != nil {
 		book.Author = *input.Author
 	}
-	if input.Price != nil {
-		book.Price = *input.Price
+	if input.Publisher != nil {
+		book.Publisher = *input.Publisher
+	}
+	if input.PublicationDate != nil {
+		book.PublicationDate = *input.PublicationDate
+	}
+	if input.Language != nil {
+		book.Language = *input.Language
+	}
+	if input.PageCount != nil {
+		book.PageCount = *input.PageCount
+	}
+	if input.Description != nil {
+		book.Description = *input.Description
+	}
+
+	// Цена, остаток и ISBN принадлежат изданиям и меняются через /api/v1/editions
+	v := validator.New()
+	v.Check(input.Price == nil, "price", "is set per edition, use /api/v1/editions/{id}")
+	v.Check(input.StockQuantity == nil, "stock_quantity", "is set per edition, use /api/v1/editions/{id}")
+	v.Check(input.ISBN == nil, "isbn", "is set per edition, use /api/v1/editions/{id}")
+
+	// Список авторов заменяется целиком. Строка author пересчитывается, если не передана явно.
+	if input.Authors != nil {
+		book.Authors = *input.Authors
+		if book.Authors == nil {
+			book.Authors = []*models.BookAuthor{}
+		}
+		err = app.resolveBookAuthors(v, book.Authors)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if input.Author == nil {
+			book.Author = models.AuthorDisplayName(book.Authors)
+		}
 	}
-	if input.StockQuantity != nil {
-		book.StockQuantity = *input.StockQuantity
+
+	if input.Categories != nil {
+		book.Categories = bookCategories(*input.Categories)
+		err = app.resolveBookCategories(v, book.Categories)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
 	}
 
-	err = app.models.Books.Update(&book)
+	if models.ValidateBook(v, book); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Books.Update(book, models.BookUpdate{
+		Authors:    input.Authors != nil,
+		Categories: input.Categories != nil,
+	})
 	if err != nil {
-		app.respondWithError(w, http.StatusInternalServerError, "500 Internal Server Error")
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.respondWithError(w, r, http.StatusNotFound, "Book not found")
+		default:
+			app.respondWithError(w, r, http.StatusInternalServerError, "500 Internal Server Error")
+		}
 		return
 	}
 
-	app.respondWithJSON(w, http.StatusOK, book)
+	app.respondWithJSON(w, r, http.StatusOK, book)
+}
+
+// bookCategories преобразует список ID категорий из запроса. Пустой список означает, что
+// книгу нужно убрать из всех категорий, поэтому результат не бывает nil.
+func bookCategories(ids []int64) []*models.BookCategory {
+	categories := make([]*models.BookCategory, len(ids))
+	for i, id := range ids {
+		categories[i] = &models.BookCategory{ID: id}
+	}
+	return categories
 }
 
 // Обработчик для удаления книги
@@ -172,15 +461,15 @@ func (app *application) deleteBookHandler(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil || id < 1 {
-		app.respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		app.respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
 		return
 	}
 
 	err = app.models.Books.Delete(int64(id))
 	if err != nil {
-		app.respondWithError(w, http.StatusInternalServerError, "500 Internal Server Error")
+		app.respondWithError(w, r, http.StatusInternalServerError, "500 Internal Server Error")
 		return
 	}
 
-	app.respondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+	app.respondWithJSON(w, r, http.StatusOK, map[string]string{"result": "success"})
 }
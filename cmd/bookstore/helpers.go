@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"github.com/Zhan1bek/BookStore/pkg/models"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Zhan1bek/BookStore/pkg/validator"
 	"github.com/gorilla/mux"
@@ -228,6 +231,80 @@ func (app *application) readFloat(qs url.Values, key string, defaultValue float6
 	return f
 }
 
+// readMoney is a helper method on the application type that reads an amount in the base
+// currency, such as "12.99", from the URL query string. The amount is parsed exactly, without
+// going through float64. If no matching key is found, then it returns a zero amount. If the value
+// isn't a decimal number with at most two decimal places, then we record an error message in the
+// provided Validator instance, and return a zero amount.
+func (app *application) readMoney(qs url.Values, key string, v *validator.Validator) models.Money {
+	s := qs.Get(key)
+	if s == "" {
+		return models.NewMoney(0)
+	}
+
+	m, err := models.ParseMoney(s, models.BaseCurrency)
+	if err != nil {
+		v.AddError(key, "must be a decimal amount with at most two decimal places")
+		return models.NewMoney(0)
+	}
+	return m
+}
+
+// readCSV is a helper method on application type that reads a comma-separated string value from
+// the URL query string and splits it into a slice. Empty items are dropped. If no matching key is
+// found, it returns the provided default value.
+func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
+	csv := qs.Get(key)
+	if csv == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, value := range strings.Split(csv, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// listScope returns the scope of a list request for its pagination cursors: the URL path and
+// the query string without the paging and sorting parameters, normalized so that the same
+// filters in a different order give the same scope.
+func (app *application) listScope(r *http.Request) string {
+	qs := url.Values{}
+	for key, values := range r.URL.Query() {
+		switch key {
+		case "page", "page_size", "sort", "after", "before":
+			continue
+		}
+		for _, value := range values {
+			if value != "" {
+				qs.Add(key, value)
+			}
+		}
+		sort.Strings(qs[key])
+	}
+	// Encode sorts the parameters by key.
+	return r.URL.Path + "?" + qs.Encode()
+}
+
+// readIDs reads a comma-separated list of positive record IDs from the URL query string. If any
+// of the values is not a valid ID, an error message is recorded in the provided Validator
+// instance and nil is returned.
+func (app *application) readIDs(qs url.Values, key string, v *validator.Validator) []int64 {
+	var ids []int64
+	for _, value := range app.readCSV(qs, key, nil) {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			v.AddError(key, "must be a comma-separated list of ids")
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func (app *application) GetToken(w http.ResponseWriter, r *http.Request) (string, error) {
 	// Add the "Vary: Authorization" header to the response. This indicates to any
 	// caches that the response may vary based on the value of the Authorization
@@ -268,3 +345,42 @@ func (app *application) GetToken(w http.ResponseWriter, r *http.Request) (string
 	}
 	return token, nil
 }
+
+// clientIP extracts the client IP address from the request, without the port.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
+
+// retryAfterSeconds formats a wait duration for the Retry-After header, rounding up to
+// whole seconds.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
+
+// background runs the provided function in a background goroutine, tracked by app.wg so that
+// graceful shutdown waits for it. Any panic in the function is recovered and logged.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%s", err), nil)
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// formatID formats a record ID for the string-only log properties.
+func formatID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Zhan1bek/BookStore/pkg/models"
+)
+
+// impersonateUserHandler issues a short-lived authentication token that lets a staff user act as
+// another user, for example to see exactly what a customer sees. Requests made with the token
+// are logged and audited, and write requests are refused unless read_only is set to false.
+func (app *application) impersonateUserHandler(w http.ResponseWriter, r *http.Request) {
+	staff := app.contextGetUser(r)
+
+	// Impersonation tokens can't be used to start another impersonation.
+	if app.contextGetImpersonation(r) != nil {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	target, ok := app.readUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ReadOnly *bool `json:"read_only"`
+	}
+	// The body is optional, an empty one means a read-only impersonation.
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	readOnly := input.ReadOnly == nil || *input.ReadOnly
+
+	if target.ID == staff.ID {
+		app.errorResponse(w, r, http.StatusBadRequest, "you cannot impersonate yourself")
+		return
+	}
+
+	// Staff may only impersonate users whose permissions they hold themselves, otherwise
+	// impersonation would be a way to gain privileges.
+	staffPermissions, err := app.models.Permissions.GetAllForUser(staff.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	targetPermissions, err := app.models.Permissions.GetAllForUser(target.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	for _, code := range targetPermissions {
+		if !staffPermissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	token, err := app.models.Tokens.New(target.ID, app.config.impersonationTTL, models.ScopeAuthentication)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Impersonations.Insert(token, staff.ID, readOnly)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.PrintInfo("impersonation started", map[string]string{
+		"user_id":         formatID(target.ID),
+		"impersonated_by": formatID(staff.ID),
+	})
+
+	env := envelope{
+		"authentication_token": token,
+		"impersonation": models.Impersonation{
+			StaffUserID:  staff.ID,
+			TargetUserID: target.ID,
+			ReadOnly:     readOnly,
+		},
+	}
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
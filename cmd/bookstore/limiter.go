@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// keyLimiter allows one event per key (for example an email address) within a fixed interval.
+// It is used for endpoints that send emails, so that they can't be used to flood an inbox.
+type keyLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	last     map[string]time.Time
+}
+
+// keyLimiterPruneInterval is how often a keyLimiter forgets the keys whose interval has passed.
+const keyLimiterPruneInterval = time.Minute
+
+// newKeyLimiter returns a keyLimiter allowing one event per key every interval. A background
+// goroutine prunes expired keys, so that the map doesn't grow without bound.
+func newKeyLimiter(interval time.Duration) *keyLimiter {
+	l := &keyLimiter{
+		interval: interval,
+		last:     make(map[string]time.Time),
+	}
+
+	go func() {
+		for range time.Tick(keyLimiterPruneInterval) {
+			l.prune()
+		}
+	}()
+
+	return l
+}
+
+// Allow reports whether an event for the key may happen now, and if so records it.
+func (l *keyLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	if t, found := l.last[key]; found && now.Sub(t) < l.interval {
+		return false
+	}
+
+	l.last[key] = now
+	return true
+}
+
+// prune forgets the keys whose interval has passed.
+func (l *keyLimiter) prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for k, t := range l.last {
+		if now.Sub(t) >= l.interval {
+			delete(l.last, k)
+		}
+	}
+}
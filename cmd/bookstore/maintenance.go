@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// startMaintenance starts the background worker that periodically purges expired data. The
+// worker is tracked by app.wg and stops when app.shutdown is closed, so graceful shutdown
+// waits for a running purge to finish.
+func (app *application) startMaintenance() {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(app.config.maintenance.interval)
+		defer ticker.Stop()
+
+		for {
+			app.runMaintenance()
+
+			select {
+			case <-ticker.C:
+			case <-app.shutdown:
+				return
+			}
+		}
+	}()
+}
+
+// runMaintenance performs a single maintenance pass. Every step runs even if an earlier one
+// failed, and the number of purged rows is logged for each of them.
+func (app *application) runMaintenance() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.PrintError(fmt.Errorf("%s", err), nil)
+		}
+	}()
+
+	now := time.Now()
+
+	steps := []struct {
+		name string
+		fn   func() (int64, error)
+	}{
+		{"expired_tokens", app.models.Tokens.DeleteExpired},
+		{"unactivated_users", func() (int64, error) {
+			return app.models.Users.DeleteUnactivated(now.Add(-app.config.maintenance.unactivatedAge))
+		}},
+		{"login_attempts", func() (int64, error) {
+			return app.models.LoginAttempts.DeleteBefore(now.Add(-app.config.login.window))
+		}},
+	}
+
+	for _, step := range steps {
+		count, err := step.fn()
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"task": step.name})
+			continue
+		}
+
+		app.logger.PrintInfo("maintenance task completed", map[string]string{
+			"task":    step.name,
+			"deleted": strconv.FormatInt(count, 10),
+		})
+	}
+}
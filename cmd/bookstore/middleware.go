@@ -5,15 +5,30 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/Zhan1bek/BookStore/pkg/i18n"
 	"github.com/Zhan1bek/BookStore/pkg/models"
 	"github.com/Zhan1bek/BookStore/pkg/validator"
 )
 
+// negotiateLocale picks the language of the response from the lang query parameter or the
+// Accept-Language header, falling back to the default locale, and stores it in the request
+// context. The chosen locale is reported in the Content-Language header.
+func (app *application) negotiateLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+
+		locale := i18n.Negotiate(r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"), app.config.defaultLocale)
+		w.Header().Set("Content-Language", locale)
+
+		next.ServeHTTP(w, app.contextSetLocale(r, locale))
+	})
+}
+
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Add the "Vary: Authorization" header to the response. This indicates to any caches
 		// that the response may vary based on the value of the Authorization header in the request.
-		w.Header().Set("Vary", "Authorization")
+		w.Header().Add("Vary", "Authorization")
 
 		// Retrieve the value of the Authorization header from teh request. This will return the
 		// empty string "" if there is no such header found.
@@ -51,9 +66,10 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		// Retrieve the details of the user associated with the authentication token.
+		// Retrieve the details of the user associated with the authentication token, and of
+		// the impersonation if the token was issued to a staff member acting as the user.
 		// call invalidAuthenticationTokenResponse if no matching record was found.
-		user, err := app.models.Users.GetForToken(models.ScopeAuthentication, token)
+		user, impersonation, err := app.models.Users.GetForAuthenticationToken(token)
 		if err != nil {
 			switch {
 			case errors.Is(err, models.ErrRecordNotFound):
@@ -67,11 +83,65 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		// Call the contextSetUser healer to add the user information to the request context.
 		r = app.contextSetUser(r, user)
 
-		// Call next handler in chain
-		next.ServeHTTP(w, r)
+		// An ordinary token, call next handler in chain.
+		if impersonation == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		app.serveImpersonated(w, app.contextSetImpersonation(r, impersonation), next)
 	})
 }
 
+// serveImpersonated handles a request made with an impersonation token. The request is tagged in
+// the logs and recorded in the audit table together with the response status, and write
+// requests are refused if the impersonation is read-only.
+func (app *application) serveImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	impersonation := app.contextGetImpersonation(r)
+
+	app.logger.PrintInfo("impersonated request", map[string]string{
+		"request_method":  r.Method,
+		"request_url":     r.URL.String(),
+		"user_id":         formatID(impersonation.TargetUserID),
+		"impersonated_by": formatID(impersonation.StaffUserID),
+	})
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		next.ServeHTTP(rec, r)
+	default:
+		if impersonation.ReadOnly {
+			app.impersonationReadOnlyResponse(rec, r)
+		} else {
+			next.ServeHTTP(rec, r)
+		}
+	}
+
+	err := app.models.Impersonations.InsertAudit(&models.ImpersonationAuditEntry{
+		StaffUserID:  impersonation.StaffUserID,
+		TargetUserID: impersonation.TargetUserID,
+		Method:       r.Method,
+		Path:         r.URL.String(),
+		Status:       rec.status,
+	})
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// statusRecorder wraps a http.ResponseWriter to remember the status code that was sent.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
 // requireAuthenticatedUser checks that the user is not anonymous (i.e., they are authenticated).
 func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
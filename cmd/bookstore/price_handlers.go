@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/models"
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+	"github.com/gorilla/mux"
+)
+
+// Обработчик для получения истории цен всех изданий книги, начиная с последних изменений
+func (app *application) bookPriceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBookParam(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters := models.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         "-changed_at",
+		SortSafelist: []string{"-changed_at"},
+	}
+	if models.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	history, metadata, err := app.models.Prices.History(book.ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"book_id": book.ID, "history": history, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для получения запланированных изменений цены издания
+func (app *application) listScheduledPricesHandler(w http.ResponseWriter, r *http.Request) {
+	edition, ok := app.readEditionParam(w, r)
+	if !ok {
+		return
+	}
+
+	prices, err := app.models.Prices.ScheduledForEdition(edition.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"scheduled_prices": prices}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для планирования изменения цены издания. С ends_at это акция, после которой
+// возвращается прежняя цена, без ends_at — постоянное изменение с указанного момента.
+func (app *application) createScheduledPriceHandler(w http.ResponseWriter, r *http.Request) {
+	edition, ok := app.readEditionParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Price    models.Money `json:"price"`
+		StartsAt time.Time    `json:"starts_at"`
+		EndsAt   *time.Time   `json:"ends_at"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	price := &models.ScheduledPrice{
+		EditionID: edition.ID,
+		Price:     input.Price,
+		StartsAt:  input.StartsAt,
+		EndsAt:    input.EndsAt,
+		CreatedBy: app.actorID(r),
+	}
+
+	v := validator.New()
+	if models.ValidateScheduledPrice(v, price, time.Now()); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Prices.Schedule(price)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrScheduleOverlap):
+			v.AddError("starts_at", "overlaps another scheduled price of this edition")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"scheduled_price": price}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для отмены запланированного изменения цены. Если акция уже идет, прежняя цена
+// возвращается сразу.
+func (app *application) cancelScheduledPriceHandler(w http.ResponseWriter, r *http.Request) {
+	edition, ok := app.readEditionParam(w, r)
+	if !ok {
+		return
+	}
+	priceID, err := strconv.ParseInt(mux.Vars(r)["price_id"], 10, 64)
+	if err != nil || priceID < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	price, err := app.models.Prices.GetScheduled(priceID)
+	if err == nil && price.EditionID != edition.ID {
+		err = models.ErrRecordNotFound
+	}
+	if err == nil {
+		err = app.models.Prices.Cancel(price)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, models.ErrScheduleFinished):
+			app.errorResponse(w, r, http.StatusConflict, "the scheduled price has already finished")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"scheduled_price": price}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// startPriceScheduler starts the background worker that applies scheduled price changes and
+// ends sales. It runs far more often than maintenance, so that a sale starts close to its
+// starts_at. Like maintenance, it is tracked by app.wg and stops when app.shutdown is closed.
+func (app *application) startPriceScheduler() {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(app.config.prices.interval)
+		defer ticker.Stop()
+
+		for {
+			app.runPriceScheduler()
+
+			select {
+			case <-ticker.C:
+			case <-app.shutdown:
+				return
+			}
+		}
+	}()
+}
+
+// runPriceScheduler applies every scheduled price change that is due.
+func (app *application) runPriceScheduler() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.PrintError(fmt.Errorf("%s", err), nil)
+		}
+	}()
+
+	count, err := app.models.Prices.ApplyScheduled(time.Now())
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"task": "scheduled_prices"})
+	}
+	if count > 0 {
+		app.logger.PrintInfo("scheduled prices applied", map[string]string{
+			"task":    "scheduled_prices",
+			"applied": strconv.FormatInt(count, 10),
+		})
+	}
+}
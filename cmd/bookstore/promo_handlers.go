@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/models"
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+)
+
+// Обработчик для создания промокода
+func (app *application) createPromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code           string          `json:"code"`
+		Kind           string          `json:"kind"`
+		Percent        *models.Percent `json:"percent"`
+		Amount         *models.Money   `json:"amount"`
+		StartsAt       *time.Time      `json:"starts_at"`
+		EndsAt         *time.Time      `json:"ends_at"`
+		MaxUses        int             `json:"max_uses"`
+		MaxUsesPerUser int             `json:"max_uses_per_user"`
+		MinOrder       models.Money    `json:"min_order"`
+		BookIDs        []int64         `json:"book_ids"`
+		AuthorIDs      []int64         `json:"author_ids"`
+		CategoryIDs    []int64         `json:"category_ids"`
+		Active         *bool           `json:"active"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	promo := &models.PromoCode{
+		Code:           input.Code,
+		Kind:           input.Kind,
+		Percent:        input.Percent,
+		Amount:         input.Amount,
+		StartsAt:       input.StartsAt,
+		EndsAt:         input.EndsAt,
+		MaxUses:        input.MaxUses,
+		MaxUsesPerUser: input.MaxUsesPerUser,
+		MinOrder:       input.MinOrder,
+		BookIDs:        input.BookIDs,
+		AuthorIDs:      input.AuthorIDs,
+		CategoryIDs:    input.CategoryIDs,
+		Active:         input.Active == nil || *input.Active,
+	}
+
+	v := validator.New()
+	if models.ValidatePromoCode(v, promo); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.PromoCodes.Insert(promo)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicatePromoCode):
+			v.AddError("code", "a promo code with this code already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/promo-codes/%d", promo.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"promo_code": promo}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для получения промокода по ID
+func (app *application) showPromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	promo, ok := app.readPromoCodeParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"promo_code": promo}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для получения списка промокодов с поиском по коду
+func (app *application) listPromoCodesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string
+		models.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Code = app.readStrings(qs, "code", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readStrings(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"id", "code", "created_at", "-id", "-code", "-created_at"}
+
+	if models.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	promos, metadata, err := app.models.PromoCodes.GetAll(input.Code, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"promo_codes": promos, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для частичного обновления промокода
+func (app *application) updatePromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	promo, ok := app.readPromoCodeParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Code           *string         `json:"code"`
+		Kind           *string         `json:"kind"`
+		Percent        *models.Percent `json:"percent"`
+		Amount         *models.Money   `json:"amount"`
+		StartsAt       *time.Time      `json:"starts_at"`
+		EndsAt         *time.Time      `json:"ends_at"`
+		MaxUses        *int            `json:"max_uses"`
+		MaxUsesPerUser *int            `json:"max_uses_per_user"`
+		MinOrder       *models.Money   `json:"min_order"`
+		BookIDs        *[]int64        `json:"book_ids"`
+		AuthorIDs      *[]int64        `json:"author_ids"`
+		CategoryIDs    *[]int64        `json:"category_ids"`
+		Active         *bool           `json:"active"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Code != nil {
+		promo.Code = *input.Code
+	}
+	// При смене вида скидки прежнее значение теряет смысл, новое нужно передать явно
+	if input.Kind != nil && *input.Kind != promo.Kind {
+		promo.Kind = *input.Kind
+		promo.Percent, promo.Amount = nil, nil
+	}
+	if input.Percent != nil {
+		promo.Percent = input.Percent
+	}
+	if input.Amount != nil {
+		promo.Amount = input.Amount
+	}
+	if input.StartsAt != nil {
+		promo.StartsAt = input.StartsAt
+	}
+	if input.EndsAt != nil {
+		promo.EndsAt = input.EndsAt
+	}
+	if input.MaxUses != nil {
+		promo.MaxUses = *input.MaxUses
+	}
+	if input.MaxUsesPerUser != nil {
+		promo.MaxUsesPerUser = *input.MaxUsesPerUser
+	}
+	if input.MinOrder != nil {
+		promo.MinOrder = *input.MinOrder
+	}
+	if input.BookIDs != nil {
+		promo.BookIDs = *input.BookIDs
+	}
+	if input.AuthorIDs != nil {
+		promo.AuthorIDs = *input.AuthorIDs
+	}
+	if input.CategoryIDs != nil {
+		promo.CategoryIDs = *input.CategoryIDs
+	}
+	if input.Active != nil {
+		promo.Active = *input.Active
+	}
+
+	v := validator.New()
+	if models.ValidatePromoCode(v, promo); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.PromoCodes.Update(promo)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicatePromoCode):
+			v.AddError("code", "a promo code with this code already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, models.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"promo_code": promo}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для удаления промокода
+func (app *application) deletePromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.PromoCodes.Delete(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "promo code successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readPromoCodeParam загружает промокод по ID из URL. Если промокод не найден или произошла
+// ошибка, ответ уже отправлен и возвращается false.
+func (app *application) readPromoCodeParam(w http.ResponseWriter, r *http.Request) (*models.PromoCode, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	promo, err := app.models.PromoCodes.Get(int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return promo, true
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/Zhan1bek/BookStore/pkg/models"
 	"net/http"
+	"strings"
 )
 
 func (app *application) BuyBook(w http.ResponseWriter, r *http.Request) {
@@ -26,7 +27,13 @@ func (app *application) BuyBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var input struct {
-		Title string `json:"title"` // Assume JSON body contains a 'title' field
+		EditionID int64  `json:"edition_id"` // The edition to buy, which takes precedence
+		ISBN      string `json:"isbn"`       // or the ISBN of the edition
+		Title     string `json:"title"`      // or the title of the book, with an optional
+		Format    string `json:"format"`     // format if the book has several editions
+		Quantity  int    `json:"quantity"`   // Number of copies, 1 if omitted
+		PromoCode string `json:"promo_code"` // Optional promo code for a discount
+		Currency  string `json:"currency"`   // Currency to pay in, the user's preferred one if omitted
 	}
 	err = json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
@@ -34,7 +41,24 @@ func (app *application) BuyBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	book, err := app.models.Books.GetByTitle(input.Title)
+	var edition *models.Edition
+	switch {
+	case input.EditionID != 0:
+		edition, err = app.models.Editions.Get(input.EditionID)
+	case input.ISBN != "":
+		var isbn string
+		isbn, err = models.NormalizeISBN(input.ISBN)
+		if err != nil {
+			app.failedValidationResponse(w, r, map[string]string{"isbn": "must be a valid ISBN-10 or ISBN-13"})
+			return
+		}
+		edition, err = app.models.Editions.GetByISBN(isbn)
+	default:
+		var ok bool
+		if edition, ok = app.chooseEdition(w, r, input.Title, input.Format); !ok {
+			return
+		}
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrRecordNotFound):
@@ -45,29 +69,82 @@ func (app *application) BuyBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if book.StockQuantity < 1 {
-		app.errorResponse(w, r, http.StatusForbidden, "No more books available in stock.")
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+	if input.Quantity < 1 || input.Quantity > 100 {
+		app.failedValidationResponse(w, r, map[string]string{"quantity": "must be between 1 and 100"})
 		return
 	}
 
-	book.StockQuantity -= 1
-	err = app.models.Books.UpdateQuantity(book.ID, book.StockQuantity)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
+	// Stock, the promo code and the purchase are handled in one transaction, so a rejected
+	// promo code leaves the stock untouched
+	currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+	if currency == "" {
+		currency = user.Currency
 	}
 
 	purchase := &models.Purchase{
-		UserID:     user.ID,
-		BookID:     book.ID,
-		Quantity:   1,
-		TotalPrice: book.Price,
+		UserID:    user.ID,
+		EditionID: edition.ID,
+		Quantity:  input.Quantity,
+		Currency:  currency,
 	}
-	err = app.models.Purchase.Insert(purchase)
+	err = app.models.Purchase.Buy(purchase, strings.TrimSpace(input.PromoCode))
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		var promoErr *models.PromoCodeError
+		switch {
+		case errors.Is(err, models.ErrOutOfStock):
+			app.errorResponse(w, r, http.StatusForbidden, "No more books available in stock.")
+		case errors.As(err, &promoErr):
+			app.failedValidationResponse(w, r, map[string]string{"promo_code": promoErr.Reason})
+		case errors.Is(err, models.ErrUnknownCurrency):
+			app.failedValidationResponse(w, r, map[string]string{"currency": "is not supported"})
+		case errors.Is(err, models.ErrAmountOutOfRange):
+			app.failedValidationResponse(w, r, map[string]string{"currency": "the order total is too large to be charged in this currency"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	app.writeJSON(w, http.StatusCreated, envelope{"purchase": purchase}, nil)
 }
+
+// chooseEdition finds the edition to buy when only the book title was given. The format
+// selects among several editions; a book with a single edition needs none. If no edition
+// fits or an error occurs, a response has already been sent and false is returned.
+func (app *application) chooseEdition(w http.ResponseWriter, r *http.Request, title, format string) (*models.Edition, bool) {
+	book, err := app.models.Books.GetByTitle(title)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	if len(book.Editions) == 0 {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	if format == "" && len(book.Editions) == 1 {
+		return book.Editions[0], true
+	}
+
+	formats := make([]string, len(book.Editions))
+	for i, edition := range book.Editions {
+		if edition.Format == format {
+			return edition, true
+		}
+		formats[i] = edition.Format
+	}
+
+	message := "must be one of " + strings.Join(formats, ", ")
+	if format == "" {
+		message = "must be provided, the book is available as " + strings.Join(formats, ", ")
+	}
+	app.failedValidationResponse(w, r, map[string]string{"format": message})
+	return nil, false
+}
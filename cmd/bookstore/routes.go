@@ -1,6 +1,7 @@
 package main
 
 import (
+	"expvar"
 	"github.com/gorilla/mux"
 	"net/http"
 )
@@ -17,27 +18,108 @@ func (app *application) routes() http.Handler {
 
 	// Настройка маршрутов для книг
 	bookRouter := r.PathPrefix("/api/v1/books").Subrouter()
-	bookRouter.HandleFunc("", app.createBookHandler).Methods("POST")               // Создание книги
-	bookRouter.HandleFunc("/{id:[0-9]+}", app.getBookHandler).Methods("GET")       // Получение книги по ID
-	bookRouter.HandleFunc("/{id:[0-9]+}", app.updateBookHandler).Methods("PUT")    // Обновление книги
-	bookRouter.HandleFunc("/{id:[0-9]+}", app.deleteBookHandler).Methods("DELETE") // Удаление книги
+	bookRouter.HandleFunc("", app.requirePermissions("books:write", app.createBookHandler)).Methods("POST")                // Создание книги
+	bookRouter.HandleFunc("/{id:[0-9]+}", app.getBookHandler).Methods("GET")                                               // Получение книги по ID
+	bookRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:write", app.updateBookHandler)).Methods("PUT")     // Обновление книги
+	bookRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:delete", app.deleteBookHandler)).Methods("DELETE") // Удаление книги
 	bookRouter.HandleFunc("/buy", app.requirePermissions("books:read", app.BuyBook)).Methods("POST")
 	bookRouter.HandleFunc("/list", app.GetBookList).Methods("GET")
+	bookRouter.HandleFunc("/suggest", app.suggestBooksHandler).Methods("GET")
 	bookRouter.HandleFunc("/{id}/rate", app.rateBook).Methods("POST")
+	bookRouter.HandleFunc("/{id:[0-9]+}/editions", app.listBookEditionsHandler).Methods("GET")
+	bookRouter.HandleFunc("/{id:[0-9]+}/editions", app.requirePermissions("books:write", app.createEditionHandler)).Methods("POST")
+	bookRouter.HandleFunc("/{id:[0-9]+}/next", app.nextInSeriesHandler).Methods("GET")
+	bookRouter.HandleFunc("/{id:[0-9]+}/price-history", app.requirePermissions("books:read", app.bookPriceHistoryHandler)).Methods("GET")
+	bookRouter.HandleFunc("/{id:[0-9]+}/translations", app.requirePermissions("books:write", app.listBookTranslationsHandler)).Methods("GET")
+	bookRouter.HandleFunc("/{id:[0-9]+}/translations/{locale:[a-z]{2,3}}", app.requirePermissions("books:write", app.putBookTranslationHandler)).Methods("PUT")
+	bookRouter.HandleFunc("/{id:[0-9]+}/translations/{locale:[a-z]{2,3}}", app.requirePermissions("books:write", app.deleteBookTranslationHandler)).Methods("DELETE")
+
+	// Настройка маршрутов для изданий
+	editionRouter := r.PathPrefix("/api/v1/editions").Subrouter()
+	editionRouter.HandleFunc("/{id:[0-9]+}", app.showEditionHandler).Methods("GET")
+	editionRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:write", app.updateEditionHandler)).Methods("PATCH")
+	editionRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:delete", app.deleteEditionHandler)).Methods("DELETE")
+	editionRouter.HandleFunc("/{id:[0-9]+}/scheduled-prices", app.requirePermissions("books:write", app.listScheduledPricesHandler)).Methods("GET")
+	editionRouter.HandleFunc("/{id:[0-9]+}/scheduled-prices", app.requirePermissions("books:write", app.createScheduledPriceHandler)).Methods("POST")
+	editionRouter.HandleFunc("/{id:[0-9]+}/scheduled-prices/{price_id:[0-9]+}", app.requirePermissions("books:write", app.cancelScheduledPriceHandler)).Methods("DELETE")
+
+	// Настройка маршрутов для серий
+	seriesRouter := r.PathPrefix("/api/v1/series").Subrouter()
+	seriesRouter.HandleFunc("", app.listSeriesHandler).Methods("GET")
+	seriesRouter.HandleFunc("", app.requirePermissions("books:write", app.createSeriesHandler)).Methods("POST")
+	seriesRouter.HandleFunc("/{id:[0-9]+}", app.showSeriesHandler).Methods("GET")
+	seriesRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:write", app.updateSeriesHandler)).Methods("PATCH")
+	seriesRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:delete", app.deleteSeriesHandler)).Methods("DELETE")
+	seriesRouter.HandleFunc("/{id:[0-9]+}/books/{book_id:[0-9]+}", app.requirePermissions("books:write", app.setSeriesVolumeHandler)).Methods("PUT")
+	seriesRouter.HandleFunc("/{id:[0-9]+}/books/{book_id:[0-9]+}", app.requirePermissions("books:write", app.removeSeriesVolumeHandler)).Methods("DELETE")
+
+	// Настройка маршрутов для авторов
+	authorRouter := r.PathPrefix("/api/v1/authors").Subrouter()
+	authorRouter.HandleFunc("", app.listAuthorsHandler).Methods("GET")
+	authorRouter.HandleFunc("", app.requirePermissions("books:write", app.createAuthorHandler)).Methods("POST")
+	authorRouter.HandleFunc("/{id:[0-9]+}", app.showAuthorHandler).Methods("GET")
+	authorRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:write", app.updateAuthorHandler)).Methods("PATCH")
+	authorRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:delete", app.deleteAuthorHandler)).Methods("DELETE")
+	authorRouter.HandleFunc("/{id:[0-9]+}/books", app.listAuthorBooksHandler).Methods("GET")
+
+	// Настройка маршрутов для категорий
+	categoryRouter := r.PathPrefix("/api/v1/categories").Subrouter()
+	categoryRouter.HandleFunc("", app.listCategoriesHandler).Methods("GET")
+	categoryRouter.HandleFunc("", app.requirePermissions("books:write", app.createCategoryHandler)).Methods("POST")
+	categoryRouter.HandleFunc("/{id:[0-9]+}", app.showCategoryHandler).Methods("GET")
+	categoryRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:write", app.updateCategoryHandler)).Methods("PATCH")
+	categoryRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("books:delete", app.deleteCategoryHandler)).Methods("DELETE")
+	categoryRouter.HandleFunc("/{id:[0-9]+}/translations", app.requirePermissions("books:write", app.listCategoryTranslationsHandler)).Methods("GET")
+	categoryRouter.HandleFunc("/{id:[0-9]+}/translations/{locale:[a-z]{2,3}}", app.requirePermissions("books:write", app.putCategoryTranslationHandler)).Methods("PUT")
+	categoryRouter.HandleFunc("/{id:[0-9]+}/translations/{locale:[a-z]{2,3}}", app.requirePermissions("books:write", app.deleteCategoryTranslationHandler)).Methods("DELETE")
+
+	// Настройка маршрутов для промокодов
+	promoRouter := r.PathPrefix("/api/v1/promo-codes").Subrouter()
+	promoRouter.HandleFunc("", app.requirePermissions("promo_codes:write", app.listPromoCodesHandler)).Methods("GET")
+	promoRouter.HandleFunc("", app.requirePermissions("promo_codes:write", app.createPromoCodeHandler)).Methods("POST")
+	promoRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("promo_codes:write", app.showPromoCodeHandler)).Methods("GET")
+	promoRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("promo_codes:write", app.updatePromoCodeHandler)).Methods("PATCH")
+	promoRouter.HandleFunc("/{id:[0-9]+}", app.requirePermissions("promo_codes:write", app.deletePromoCodeHandler)).Methods("DELETE")
+
+	// Настройка маршрутов для курсов валют
+	currencyRouter := r.PathPrefix("/api/v1/currencies").Subrouter()
+	currencyRouter.HandleFunc("", app.listCurrencyRatesHandler).Methods("GET")
+	currencyRouter.HandleFunc("/{currency:[A-Za-z]{3}}", app.showCurrencyRateHandler).Methods("GET")
+	currencyRouter.HandleFunc("/{currency:[A-Za-z]{3}}", app.requirePermissions("currencies:write", app.putCurrencyRateHandler)).Methods("PUT")
+	currencyRouter.HandleFunc("/{currency:[A-Za-z]{3}}", app.requirePermissions("currencies:write", app.deleteCurrencyRateHandler)).Methods("DELETE")
 
 	//Users handlers
 	users1 := r.PathPrefix("/api/v1/users").Subrouter()
 	// User handlers with Authentication
 	users1.HandleFunc("", app.registerUserHandler).Methods("POST")
 	users1.HandleFunc("/activated", app.activateUserHandler).Methods("PUT")
+	users1.HandleFunc("/activation", app.resendActivationTokenHandler).Methods("POST")
 	users1.HandleFunc("/login", app.createAuthenticationTokenHandler).Methods("POST")
+	users1.HandleFunc("/login/link", app.createMagicLinkHandler).Methods("POST")
+	users1.HandleFunc("/login/link/exchange", app.exchangeMagicLinkHandler).Methods("POST")
 	users1.HandleFunc("/purchases", app.requirePermissions("books:read", app.ListPurchases)).Methods("GET")
+	users1.HandleFunc("/me", app.requireAuthenticatedUser(app.showCurrentUserHandler)).Methods("GET")
+	users1.HandleFunc("/me", app.requireAuthenticatedUser(app.updateCurrentUserHandler)).Methods("PATCH")
+	users1.HandleFunc("/me", app.requireAuthenticatedUser(app.deleteCurrentUserHandler)).Methods("DELETE")
+	users1.HandleFunc("/me/export", app.requireAuthenticatedUser(app.exportCurrentUserHandler)).Methods("GET")
+	users1.HandleFunc("/me/password", app.requireAuthenticatedUser(app.changePasswordHandler)).Methods("PUT")
+	users1.HandleFunc("/me/email", app.requireAuthenticatedUser(app.requestEmailChangeHandler)).Methods("POST")
+	users1.HandleFunc("/email", app.confirmEmailChangeHandler).Methods("PUT")
+	users1.HandleFunc("/{id:[0-9]+}/unlock", app.requirePermissions("users:unlock", app.unlockUserHandler)).Methods("PUT")
+	users1.HandleFunc("/{id:[0-9]+}/permissions", app.requirePermissions("users:permissions", app.listUserPermissionsHandler)).Methods("GET")
+	users1.HandleFunc("/{id:[0-9]+}/permissions", app.requirePermissions("users:permissions", app.grantUserPermissionsHandler)).Methods("POST")
+	users1.HandleFunc("/{id:[0-9]+}/permissions", app.requirePermissions("users:permissions", app.revokeUserPermissionsHandler)).Methods("DELETE")
+	users1.HandleFunc("/{id:[0-9]+}/impersonate", app.requirePermissions("users:impersonate", app.impersonateUserHandler)).Methods("POST")
 
 	commentsRouter := r.PathPrefix("/api/v1/comments").Subrouter()
 	commentsRouter.HandleFunc("", app.CreateComment).Methods("POST")
 	commentsRouter.HandleFunc("", app.GetComments).Methods("GET")
 	commentsRouter.HandleFunc("", app.DeleteComment).Methods("DELETE")
 
-	// Wrap the router with the panic recovery middleware and rate limit middleware.
-	return app.authenticate(r)
+	// Application metrics, including the permission cache hit and miss counters.
+	r.Handle("/debug/vars", app.requirePermissions("metrics:read", expvar.Handler().ServeHTTP)).Methods("GET")
+
+	// Wrap the router with the panic recovery middleware and rate limit middleware. The locale
+	// is negotiated first so that authentication errors are translated too.
+	return app.negotiateLocale(app.authenticate(r))
 }
@@ -48,6 +48,9 @@ func (app *application) serve() error {
 			"signal": s.String(),
 		})
 
+		// Tell the background workers to stop.
+		close(app.shutdown)
+
 		// Create a context with a 5-second timeout.
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
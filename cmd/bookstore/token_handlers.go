@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Zhan1bek/BookStore/pkg/models"
@@ -28,6 +29,33 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
+	// Record the attempt before anything else. It counts as a failure until the login
+	// succeeds, so parallel guesses can't all pass the check below before any of them is
+	// recorded: each one sees the attempts that came before it. Failures are tracked by the
+	// email address as provided, so the response is the same whether or not the account exists.
+	ip := clientIP(r)
+	attemptID, err := app.models.LoginAttempts.Insert(input.Email, ip)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Refuse to check the password at all while the account or the client IP is locked
+	// out or still inside its progressive delay. The refused attempt itself is forgotten, so
+	// that retrying doesn't extend the lockout.
+	retryAfter, err := app.loginRetryAfter(input.Email, ip, attemptID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if retryAfter > 0 {
+		err = app.models.LoginAttempts.Delete(attemptID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.tooManyLoginAttemptsResponse(w, r, retryAfter)
+		return
+	}
 	// Lookup the user record based on the email address. If no matching user was
 	// found, then we call the app.invalidCredentialsResponse() helper to send a 401
 	// Unauthorized response to the client (we will create this helper in a moment).
@@ -35,6 +63,9 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrRecordNotFound):
+			// Spend as long as checking the password of an existing account would, so that
+			// the response time doesn't reveal whether the email address is registered.
+			models.MatchesDummyPassword(input.Password)
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -53,6 +84,24 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
+	// The stored hash may be a legacy bcrypt hash or use outdated Argon2id parameters. Now
+	// that we know the plaintext password, upgrade it. A failure here must not stop the
+	// user from logging in, so it is only logged.
+	if user.Password.NeedsRehash() {
+		err = user.Password.Set(input.Password)
+		if err == nil {
+			err = app.models.Users.Update(user)
+		}
+		if err != nil {
+			app.logError(r, err)
+		}
+	}
+	// The login succeeded, so forget about any earlier failures for this account.
+	err = app.models.LoginAttempts.DeleteAllForEmail(input.Email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 	// Otherwise, if the password is correct, we generate a new token with a 24-hour
 	// expiry time and the scope 'authentication'.
 	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, models.ScopeAuthentication)
@@ -67,3 +116,168 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// loginRetryAfter returns how long the client has to wait before the login attempt with the
+// given ID for the email address from the given IP is allowed, based on the attempts recorded
+// before it. A zero duration means the attempt may proceed.
+func (app *application) loginRetryAfter(email, ip string, attemptID int64) (time.Duration, error) {
+	since := time.Now().Add(-app.config.login.window)
+
+	byEmail, err := app.models.LoginAttempts.FailuresForEmail(email, ip, since, attemptID)
+	if err != nil {
+		return 0, err
+	}
+
+	byIP, err := app.models.LoginAttempts.FailuresForIP(ip, since, attemptID)
+	if err != nil {
+		return 0, err
+	}
+
+	wait := app.loginWait(byEmail, app.config.login.maxAttempts)
+	if ipWait := app.loginWait(byIP, app.config.login.maxIPAttempts); ipWait > wait {
+		wait = ipWait
+	}
+
+	return wait, nil
+}
+
+// loginWait calculates the wait imposed by a set of failures. Below the limit every failure
+// doubles the delay, starting at the configured base delay; once the limit is reached the key
+// is locked out for the configured lockout duration after the last failure.
+func (app *application) loginWait(failures models.LoginFailures, limit int) time.Duration {
+	if failures.Count == 0 {
+		return 0
+	}
+
+	delay := app.config.login.lockout
+	if failures.Count < limit {
+		// Cap the shift so that a large limit can't overflow the duration.
+		shift := failures.Count - 1
+		if shift > 16 {
+			shift = 16
+		}
+		if d := app.config.login.delay << shift; d < delay {
+			delay = d
+		}
+	}
+
+	wait := time.Until(failures.Last.Add(delay))
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+// createMagicLinkHandler emails a short-lived, single-use login token to the user, so that they
+// can log in without their password. The response doesn't say whether the account exists.
+func (app *application) createMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if models.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.magicLinkLimiter.Allow(strings.ToLower(input.Email)) {
+		app.rateLimitExceededResponse(w, r)
+		return
+	}
+
+	env := envelope{"message": "if an account exists for this email address, a login link has been sent to it"}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			err = app.writeJSON(w, http.StatusAccepted, env, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+			}
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	token, err := app.models.Tokens.New(user.ID, app.config.magicLink.ttl, models.ScopeMagicLink)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		data := map[string]interface{}{
+			"name":  user.Name,
+			"token": token.Plaintext,
+			"ttl":   app.config.magicLink.ttl.String(),
+		}
+
+		err := app.mailer.Send(user.Email, "magic_link.tmpl", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// exchangeMagicLinkHandler exchanges a login link token for a normal authentication token.
+func (app *application) exchangeMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if models.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Consume() deletes the token in the same statement that looks it up, so a link can't be
+	// used twice.
+	userID, err := app.models.Tokens.Consume(models.ScopeMagicLink, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Any other login links sent to the user are no longer needed.
+	err = app.models.Tokens.DeleteAllForUser(models.ScopeMagicLink, userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.models.Tokens.New(userID, 24*time.Hour, models.ScopeAuthentication)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
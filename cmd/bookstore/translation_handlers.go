@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Zhan1bek/BookStore/pkg/models"
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+	"github.com/gorilla/mux"
+)
+
+// Обработчик для получения всех переводов книги
+func (app *application) listBookTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBookParam(w, r)
+	if !ok {
+		return
+	}
+
+	translations, err := app.models.Translations.BookTranslations(book.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"default_locale": app.config.defaultLocale, "translations": translations}
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для добавления или замены перевода книги на язык из URL
+func (app *application) putBookTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBookParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	translation := &models.BookTranslation{
+		BookID:      book.ID,
+		Locale:      mux.Vars(r)["locale"],
+		Title:       input.Title,
+		Description: input.Description,
+	}
+
+	v := validator.New()
+	if models.ValidateBookTranslation(v, translation, app.config.defaultLocale); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Translations.SetBookTranslation(translation)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для удаления перевода книги. После этого книга показывается на языке по умолчанию.
+func (app *application) deleteBookTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Translations.DeleteBookTranslation(int64(id), mux.Vars(r)["locale"])
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "translation successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для получения всех переводов категории
+func (app *application) listCategoryTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	category, ok := app.readCategoryParam(w, r)
+	if !ok {
+		return
+	}
+
+	translations, err := app.models.Translations.CategoryTranslations(category.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"default_locale": app.config.defaultLocale, "translations": translations}
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для добавления или замены перевода категории на язык из URL
+func (app *application) putCategoryTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	category, ok := app.readCategoryParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name string `json:"name"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	translation := &models.CategoryTranslation{
+		CategoryID: category.ID,
+		Locale:     mux.Vars(r)["locale"],
+		Name:       input.Name,
+	}
+
+	v := validator.New()
+	if models.ValidateCategoryTranslation(v, translation, app.config.defaultLocale); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Translations.SetCategoryTranslation(translation)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Обработчик для удаления перевода категории
+func (app *application) deleteCategoryTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Translations.DeleteCategoryTranslation(int64(id), mux.Vars(r)["locale"])
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "translation successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
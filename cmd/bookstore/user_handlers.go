@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Zhan1bek/BookStore/pkg/models"
@@ -73,15 +74,21 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var res struct {
-		Token string       `json:"token"`
-		User  *models.User `json:"user"`
-	}
+	// The activation token is emailed the same way as by resendActivationTokenHandler, so only
+	// the owner of the email address can activate the account.
+	app.background(func() {
+		data := map[string]interface{}{
+			"name":  user.Name,
+			"token": token.Plaintext,
+		}
 
-	res.Token = token.Plaintext
-	res.User = user
+		err := app.mailer.Send(user.Email, "user_activation.tmpl", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"user": res}, nil)
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -144,6 +151,81 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// resendActivationTokenHandler issues a fresh activation token for an account that has not been
+// activated yet and emails it to the user. Older activation tokens stop working. The response
+// is the same whether or not such an account exists, so the endpoint can't be used to find out
+// which email addresses are registered.
+func (app *application) resendActivationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if models.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Email addresses are case-insensitive in the users table, so they are limited the same way.
+	if !app.activationLimiter.Allow(strings.ToLower(input.Email)) {
+		app.rateLimitExceededResponse(w, r)
+		return
+	}
+
+	env := envelope{"message": "if an unactivated account exists for this email address, an activation token has been sent to it"}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			err = app.writeJSON(w, http.StatusAccepted, env, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+			}
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !user.Activated {
+		// Invalidate the activation tokens issued so far before creating a new one.
+		err = app.models.Tokens.DeleteAllForUser(models.ScopeActivation, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, models.ScopeActivation)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.background(func() {
+			data := map[string]interface{}{
+				"name":  user.Name,
+				"token": token.Plaintext,
+			}
+
+			err := app.mailer.Send(user.Email, "user_activation.tmpl", data)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		})
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) ListPurchases(w http.ResponseWriter, r *http.Request) {
 	token, err := app.GetToken(w, r)
 	if err != nil {
@@ -162,13 +244,49 @@ func (app *application) ListPurchases(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Получаем все покупки пользователя из базы данных
-	purchases, err := app.models.Purchase.GetAllForUser(user.ID)
+	v := validator.New()
+	qs := r.URL.Query()
+	filters := models.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readStrings(qs, "sort", "-created_at"),
+		SortSafelist: []string{"id", "created_at", "total_price", "-id", "-created_at", "-total_price"},
+		After:        app.readStrings(qs, "after", ""),
+		Before:       app.readStrings(qs, "before", ""),
+		Scope:        app.listScope(r),
+	}
+	if models.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Получаем покупки пользователя из базы данных
+	purchases, metadata, err := app.models.Purchase.ListForUser(user.ID, filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	// Возвращаем данные о покупках в формате JSON
-	app.writeJSON(w, http.StatusOK, envelope{"purchases": purchases}, nil)
+	app.writeJSON(w, http.StatusOK, envelope{"purchases": purchases, "metadata": metadata}, nil)
+}
+
+// unlockUserHandler lets staff lift a login lockout early by clearing the failed attempts
+// recorded for the user's email address.
+func (app *application) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.LoginAttempts.DeleteAllForEmail(user.Email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user account unlocked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
@@ -0,0 +1,198 @@
+// Command i18ncheck reports messages of the API that are missing from a translation catalog.
+// It reads the sources under cmd and pkg and collects the messages passed to the validator
+// (Check, AddError), to errorResponse, respondWithError and failedValidationResponse, and the
+// messages of filter expression errors. A message that neither an entry nor a rule of a catalog
+// translates is printed, and the command exits with status 1.
+//
+// Run it from the root of the repository:
+//
+//	go run ./cmd/i18ncheck
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Zhan1bek/BookStore/pkg/i18n"
+)
+
+// hole marks the variable parts of a message, such as a formatted number or a joined list.
+const hole = "\x00"
+
+// fillers are tried in place of the holes of a message. The message is translated if the
+// catalog translates it with one of them.
+var fillers = []string{"1", "x", `"x"`, "USD", "end of expression"}
+
+// filterErrorPrefix is added by filterexpr.Error.Error to the messages of filter expressions.
+const filterErrorPrefix = "at position 1: "
+
+type message struct {
+	pos  token.Position
+	text string
+}
+
+func main() {
+	fset := token.NewFileSet()
+	var messages []message
+
+	for _, root := range []string{"cmd", "pkg"} {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return nil
+			}
+			file, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				return err
+			}
+			messages = append(messages, collect(fset, file)...)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	missing := 0
+	for _, locale := range i18n.Locales {
+		for _, m := range messages {
+			if !translated(locale, m.text) {
+				fmt.Printf("%s: %s: %q\n", m.pos, locale, strings.ReplaceAll(m.text, hole, "..."))
+				missing++
+			}
+		}
+	}
+	if missing > 0 {
+		fmt.Printf("%d messages are not translated\n", missing)
+		os.Exit(1)
+	}
+}
+
+// translated reports whether the locale translates the message with any of the fillers.
+func translated(locale, text string) bool {
+	if !strings.Contains(text, hole) {
+		return i18n.Translated(locale, text)
+	}
+	for _, f := range fillers {
+		if i18n.Translated(locale, strings.ReplaceAll(text, hole, f)) {
+			return true
+		}
+	}
+	return false
+}
+
+// collect returns the messages of a file.
+func collect(fset *token.FileSet, file *ast.File) []message {
+	var messages []message
+	add := func(expr ast.Expr, prefix string) {
+		if text, ok := literal(expr); ok {
+			messages = append(messages, message{pos: fset.Position(expr.Pos()), text: prefix + text})
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			switch {
+			case sel.Sel.Name == "Check" && len(n.Args) == 3:
+				add(n.Args[2], "")
+			case sel.Sel.Name == "AddError" && len(n.Args) == 2:
+				add(n.Args[1], "")
+			case (sel.Sel.Name == "errorResponse" || sel.Sel.Name == "respondWithError") && len(n.Args) == 4:
+				add(n.Args[3], "")
+			case sel.Sel.Name == "failedValidationResponse" && len(n.Args) == 3:
+				if lit, ok := n.Args[2].(*ast.CompositeLit); ok {
+					for _, elt := range lit.Elts {
+						if kv, ok := elt.(*ast.KeyValueExpr); ok {
+							add(kv.Value, "")
+						}
+					}
+				}
+			}
+		case *ast.CompositeLit:
+			if id, ok := n.Type.(*ast.Ident); ok && id.Name == "Error" && file.Name.Name == "filterexpr" {
+				for _, elt := range n.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Msg" {
+							add(kv.Value, filterErrorPrefix)
+						}
+					}
+				}
+			}
+		}
+		return true
+	})
+	return messages
+}
+
+// literal returns the text of a message built from string literals, with holes in place of
+// the parts that are only known at run time. It returns false if the message has no literal
+// text at all, such as err.Error().
+func literal(expr ast.Expr) (string, bool) {
+	text := eval(expr)
+	return text, strings.Trim(text, hole) != ""
+}
+
+func eval(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			if s, err := strconv.Unquote(e.Value); err == nil {
+				return s
+			}
+		}
+	case *ast.ParenExpr:
+		return eval(e.X)
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			return eval(e.X) + eval(e.Y)
+		}
+	case *ast.Ident:
+		// A local variable such as message := "..." is followed to its assignment.
+		if e.Obj != nil {
+			if assign, ok := e.Obj.Decl.(*ast.AssignStmt); ok && len(assign.Lhs) == 1 && len(assign.Rhs) == 1 {
+				return eval(assign.Rhs[0])
+			}
+		}
+	case *ast.CallExpr:
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Sprintf" && len(e.Args) > 0 {
+			return format(eval(e.Args[0]))
+		}
+	}
+	return hole
+}
+
+// format replaces the verbs of a format string with holes.
+func format(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if s[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		for i < len(s) && strings.IndexByte("+-# 0123456789.", s[i]) >= 0 {
+			i++
+		}
+		b.WriteString(hole)
+	}
+	return b.String()
+}
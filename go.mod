@@ -11,4 +11,5 @@ require (
 	github.com/peterbourgon/ff/v3 v3.4.0 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	golang.org/x/crypto v0.22.0 // indirect
+	golang.org/x/sys v0.19.0 // indirect
 )
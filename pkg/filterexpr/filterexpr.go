@@ -0,0 +1,142 @@
+// Package filterexpr parses filter expressions such as
+//
+//	price<20 and (author~"King" or rating>=4) and stock>0
+//
+// into a syntax tree and compiles them to parameterized SQL. Only the fields of an allowlist
+// can be referenced, and values are always passed as query arguments, never spliced into the
+// SQL text.
+//
+// The grammar is:
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field op value
+//	op         = "=" | "!=" | "<" | "<=" | ">" | ">=" | "~" | "!~"
+//	value      = number | string | "true" | "false"
+//
+// Strings are quoted with double or single quotes, with a backslash escaping the next
+// character. The ~ and !~ operators test whether a string field contains the value, ignoring
+// case. Keywords are case-insensitive.
+package filterexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the type of a field, which determines the operators and values it accepts.
+type Type int
+
+const (
+	Number Type = iota
+	String
+	Date
+	Bool
+)
+
+// Field describes a field that filter expressions may reference. Column is the SQL
+// expression the field compiles to.
+type Field struct {
+	Column string
+	Type   Type
+}
+
+// Limits on the size of an expression, so that a single request cannot produce an
+// arbitrarily expensive query.
+const (
+	MaxLength      = 1000
+	maxDepth       = 20
+	maxComparisons = 50
+)
+
+// Error is a syntax or type error in a filter expression. Pos is the 1-based position of the
+// offending character.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Pos, e.Msg)
+}
+
+// Expr is a parsed filter expression.
+type Expr interface {
+	// SQL returns the SQL condition for the expression. add is called with each value and
+	// returns the placeholder to use for it.
+	SQL(add func(value interface{}) string) string
+}
+
+type binaryExpr struct {
+	op          string
+	left, right Expr
+}
+
+func (e binaryExpr) SQL(add func(interface{}) string) string {
+	return "(" + e.left.SQL(add) + " " + e.op + " " + e.right.SQL(add) + ")"
+}
+
+type notExpr struct {
+	expr Expr
+}
+
+func (e notExpr) SQL(add func(interface{}) string) string {
+	return "NOT (" + e.expr.SQL(add) + ")"
+}
+
+type comparison struct {
+	field Field
+	op    string
+	value interface{}
+}
+
+func (c comparison) SQL(add func(interface{}) string) string {
+	switch {
+	case c.op == "~" || c.op == "!~":
+		not := ""
+		if c.op == "!~" {
+			not = "NOT "
+		}
+		return c.field.Column + " " + not + "ILIKE " + add("%"+escapeLike(c.value.(string))+"%")
+	case c.field.Type == String:
+		return "LOWER(" + c.field.Column + ") " + sqlOp(c.op) + " LOWER(" + add(c.value) + ")"
+	default:
+		return c.field.Column + " " + sqlOp(c.op) + " " + add(c.value)
+	}
+}
+
+func sqlOp(op string) string {
+	if op == "!=" {
+		return "<>"
+	}
+	return op
+}
+
+// escapeLike escapes the LIKE wildcards in s, so that it only matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Parse parses the filter expression, checking field names against fields and values
+// against the field types. Any error is an *Error.
+func Parse(src string, fields map[string]Field) (Expr, error) {
+	if len(src) > MaxLength {
+		return nil, &Error{Pos: MaxLength + 1, Msg: fmt.Sprintf("expression must not be more than %d bytes long", MaxLength)}
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, fields: fields}
+	expr, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s", tok)}
+	}
+	return expr, nil
+}
@@ -0,0 +1,232 @@
+package filterexpr
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testFields = map[string]Field{
+	"price":     {Column: "price", Type: Number},
+	"title":     {Column: "title", Type: String},
+	"published": {Column: "published", Type: Date},
+	"in_stock":  {Column: "in_stock", Type: Bool},
+}
+
+// compile parses src and returns the SQL condition and its arguments.
+func compile(src string) (string, []interface{}, error) {
+	expr, err := Parse(src, testFields)
+	if err != nil {
+		return "", nil, err
+	}
+	var args []interface{}
+	sql := expr.SQL(func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	})
+	return sql, args, nil
+}
+
+func TestParseSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		sql  string
+		args []interface{}
+	}{
+		{
+			name: "comparison",
+			src:  "price < 20",
+			sql:  "price < $1",
+			args: []interface{}{20.0},
+		},
+		{
+			name: "not equal",
+			src:  "price != 20",
+			sql:  "price <> $1",
+			args: []interface{}{20.0},
+		},
+		{
+			name: "double equals",
+			src:  "price==-1.5",
+			sql:  "price = $1",
+			args: []interface{}{-1.5},
+		},
+		{
+			name: "and binds tighter than or",
+			src:  "price < 1 or price > 2 and price < 3",
+			sql:  "(price < $1 OR (price > $2 AND price < $3))",
+			args: []interface{}{1.0, 2.0, 3.0},
+		},
+		{
+			name: "and before or",
+			src:  "price < 1 and price > 2 or price < 3",
+			sql:  "((price < $1 AND price > $2) OR price < $3)",
+			args: []interface{}{1.0, 2.0, 3.0},
+		},
+		{
+			name: "parentheses",
+			src:  "price < 1 and (price > 2 or price < 3)",
+			sql:  "(price < $1 AND (price > $2 OR price < $3))",
+			args: []interface{}{1.0, 2.0, 3.0},
+		},
+		{
+			name: "or is left-associative",
+			src:  "price = 1 or price = 2 or price = 3",
+			sql:  "((price = $1 OR price = $2) OR price = $3)",
+			args: []interface{}{1.0, 2.0, 3.0},
+		},
+		{
+			name: "not binds tighter than and",
+			src:  "not price < 1 and price > 2",
+			sql:  "(NOT (price < $1) AND price > $2)",
+			args: []interface{}{1.0, 2.0},
+		},
+		{
+			name: "not of a group",
+			src:  "NOT (price < 1 OR in_stock = true)",
+			sql:  "NOT ((price < $1 OR in_stock = $2))",
+			args: []interface{}{1.0, true},
+		},
+		{
+			name: "double not",
+			src:  "not not in_stock = FALSE",
+			sql:  "NOT (NOT (in_stock = $1))",
+			args: []interface{}{false},
+		},
+		{
+			name: "string equality ignores case",
+			src:  `title = "Dune"`,
+			sql:  "LOWER(title) = LOWER($1)",
+			args: []interface{}{"Dune"},
+		},
+		{
+			name: "contains",
+			src:  `title ~ 'king'`,
+			sql:  "title ILIKE $1",
+			args: []interface{}{"%king%"},
+		},
+		{
+			name: "does not contain escapes wildcards",
+			src:  `title !~ "50%_off"`,
+			sql:  "title NOT ILIKE $1",
+			args: []interface{}{`%50\%\_off%`},
+		},
+		{
+			name: "escaped quote",
+			src:  `title = "say \"hi\""`,
+			sql:  "LOWER(title) = LOWER($1)",
+			args: []interface{}{`say "hi"`},
+		},
+		{
+			name: "date",
+			src:  `published >= "2020-01-31"`,
+			sql:  "published >= $1",
+			args: []interface{}{"2020-01-31"},
+		},
+		{
+			name: "field names ignore case",
+			src:  "PRICE > .5",
+			sql:  "price > $1",
+			args: []interface{}{0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := compile(tt.src)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.src, err)
+			}
+			if sql != tt.sql {
+				t.Errorf("Parse(%q) SQL = %q, want %q", tt.src, sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("Parse(%q) args = %#v, want %#v", tt.src, args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("(", depth) + "price > 1" + strings.Repeat(")", depth)
+	}
+	comparisons := func(n int) string {
+		return strings.TrimSuffix(strings.Repeat("price > 1 and ", n), " and ")
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		pos  int
+		msg  string
+	}{
+		{"empty", "", 1, "expected a field name but found end of expression"},
+		{"unknown field", "rating > 4", 1, `unknown field "rating", expected one of in_stock, price, published, title`},
+		{"missing operator", "price 20", 7, `expected an operator but found "20"`},
+		{"operator not allowed", `title < "a"`, 7, `operator "<" cannot be used with field "title"`},
+		{"single bang", "not price ! 1", 11, `unexpected "!", use "not", "!=" or "!~"`},
+		{"number expected", `price > "a"`, 9, `expected a number but found string "a"`},
+		{"quoted string expected", "title = Dune", 9, `expected a quoted string but found "Dune"`},
+		{"bad date", `published = "2020-02-30"`, 13, `expected a date such as "2006-01-02" but found string "2020-02-30"`},
+		{"bool expected", "in_stock = 1", 12, `expected true or false but found "1"`},
+		{"unterminated string", `title = "Dune`, 9, "unterminated string"},
+		{"unterminated string with escaped quote", `title = 'Dune\'`, 9, "unterminated string"},
+		{"unexpected character", "price > 1 & price < 2", 11, `unexpected character '&'`},
+		{"missing closing parenthesis", "(price > 1", 11, `expected ")" but found end of expression`},
+		{"trailing token", "price > 1)", 10, `unexpected ")"`},
+		{"dangling and", "price > 1 and", 14, "expected a field name but found end of expression"},
+		{"positions count characters", `title = "ё" and x`, 17, `unknown field "x", expected one of in_stock, price, published, title`},
+		{"nested too deeply", nested(maxDepth + 1), maxDepth + 2, "expression is nested too deeply"},
+		{"not nested too deeply", strings.Repeat("not ", maxDepth+1) + "price > 1", 4*(maxDepth+1) + 1, "expression is nested too deeply"},
+		{"too many comparisons", comparisons(maxComparisons + 1), 14*maxComparisons + 1, "expression must not have more than 50 comparisons"},
+		{"too long", strings.Repeat(" ", MaxLength+1), MaxLength + 1, "expression must not be more than 1000 bytes long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src, testFields)
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse(%q) error = %v, want an *Error", tt.src, err)
+			}
+			if perr.Pos != tt.pos || perr.Msg != tt.msg {
+				t.Errorf("Parse(%q) error = %d: %q, want %d: %q", tt.src, perr.Pos, perr.Msg, tt.pos, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	src := strings.Repeat("(", maxDepth) + "price > 1" + strings.Repeat(")", maxDepth)
+	if _, err := Parse(src, testFields); err != nil {
+		t.Errorf("Parse at the maximum depth: %v", err)
+	}
+
+	src = strings.TrimSuffix(strings.Repeat("price > 1 or ", maxComparisons), " or ")
+	if _, err := Parse(src, testFields); err != nil {
+		t.Errorf("Parse with the maximum number of comparisons: %v", err)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"100%", `100\%`},
+		{"snake_case", `snake\_case`},
+		{`back\slash`, `back\\slash`},
+		{`\%_`, `\\\%\_`},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.in); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package filterexpr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// operators lists the operators each field type accepts.
+var operators = map[Type][]string{
+	Number: {"=", "!=", "<", "<=", ">", ">="},
+	Date:   {"=", "!=", "<", "<=", ">", ">="},
+	String: {"=", "!=", "~", "!~"},
+	Bool:   {"=", "!="},
+}
+
+type parser struct {
+	tokens      []token
+	next        int
+	fields      map[string]Field
+	comparisons int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) parseOr(depth int) (Expr, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("or") {
+		p.advance()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (Expr, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("and") {
+		p.advance()
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary(depth int) (Expr, error) {
+	tok := p.peek()
+	if depth > maxDepth {
+		return nil, &Error{Pos: tok.pos, Msg: "expression is nested too deeply"}
+	}
+
+	switch {
+	case tok.keyword("not"):
+		p.advance()
+		expr, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+
+	case tok.kind == tokLParen:
+		p.advance()
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokRParen {
+			return nil, &Error{Pos: closing.pos, Msg: fmt.Sprintf(`expected ")" but found %s`, closing)}
+		}
+		return expr, nil
+
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	name := p.advance()
+	if name.kind != tokIdent {
+		return nil, &Error{Pos: name.pos, Msg: fmt.Sprintf("expected a field name but found %s", name)}
+	}
+	field, ok := p.fields[strings.ToLower(name.text)]
+	if !ok {
+		return nil, &Error{Pos: name.pos, Msg: fmt.Sprintf("unknown field %q, expected one of %s", name.text, p.fieldNames())}
+	}
+
+	op := p.advance()
+	if op.kind != tokOp {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("expected an operator but found %s", op)}
+	}
+	if !contains(operators[field.Type], op.text) {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("operator %q cannot be used with field %q", op.text, name.text)}
+	}
+
+	valueTok := p.advance()
+	value, err := convert(valueTok, field.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	p.comparisons++
+	if p.comparisons > maxComparisons {
+		return nil, &Error{Pos: name.pos, Msg: fmt.Sprintf("expression must not have more than %d comparisons", maxComparisons)}
+	}
+
+	return comparison{field: field, op: op.text, value: value}, nil
+}
+
+// convert checks that the token is a valid value for the field type and returns it as the
+// query argument.
+func convert(tok token, typ Type) (interface{}, error) {
+	switch typ {
+	case Number:
+		if tok.kind == tokNumber {
+			if f, err := strconv.ParseFloat(tok.text, 64); err == nil {
+				return f, nil
+			}
+		}
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected a number but found %s", tok)}
+
+	case String:
+		if tok.kind == tokString {
+			return tok.text, nil
+		}
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected a quoted string but found %s", tok)}
+
+	case Date:
+		if tok.kind == tokString {
+			if _, err := time.Parse("2006-01-02", tok.text); err == nil {
+				return tok.text, nil
+			}
+		}
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf(`expected a date such as "2006-01-02" but found %s`, tok)}
+
+	default:
+		if tok.keyword("true") || tok.keyword("false") {
+			return strings.EqualFold(tok.text, "true"), nil
+		}
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected true or false but found %s", tok)}
+	}
+}
+
+func (p *parser) fieldNames() string {
+	names := make([]string, 0, len(p.fields))
+	for name := range p.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+// Package i18n picks the language of a response and translates the messages of the API.
+// Messages are written in English in the code and English is the source language of the
+// catalogs: a message without a translation is returned unchanged.
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locales lists the supported locales.
+var Locales = []string{"en", "ru"}
+
+// Supported reports whether the locale is one of Locales.
+func Supported(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate returns the locale of a response. An explicit lang parameter wins if it is
+// supported, then the best supported language of the Accept-Language header. Region subtags
+// are ignored, so ru-RU selects ru. If nothing matches, fallback is returned.
+func Negotiate(lang, acceptLanguage, fallback string) string {
+	if lang = strings.ToLower(strings.TrimSpace(lang)); Supported(lang) {
+		return lang
+	}
+
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag == "" || q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: strings.ToLower(tag), q: q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	for _, t := range tags {
+		if t.tag == "*" {
+			return fallback
+		}
+		primary, _, _ := strings.Cut(t.tag, "-")
+		if Supported(primary) {
+			return primary
+		}
+	}
+	return fallback
+}
+
+// rule translates messages with variable parts, such as "must be one of a, b". $1 and so on
+// in the replacement refer to the groups of the pattern.
+type rule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// catalog holds the translations of one locale.
+type catalog struct {
+	messages map[string]string
+	rules    []rule
+}
+
+// catalogs maps locales to their translations. English needs none.
+var catalogs = map[string]*catalog{
+	"ru": ru,
+}
+
+// Translate returns the message in the locale, or the message itself if it has no translation.
+func Translate(locale, message string) string {
+	c := catalogs[locale]
+	if c == nil {
+		return message
+	}
+	if translated, ok := c.messages[message]; ok {
+		return translated
+	}
+	for _, r := range c.rules {
+		if r.pattern.MatchString(message) {
+			return r.pattern.ReplaceAllString(message, r.replacement)
+		}
+	}
+	return message
+}
+
+// Translated reports whether the locale has a translation for the message. Every message is
+// translated into English, the source language.
+func Translated(locale, message string) bool {
+	c := catalogs[locale]
+	if c == nil {
+		return true
+	}
+	if _, ok := c.messages[message]; ok {
+		return true
+	}
+	for _, r := range c.rules {
+		if r.pattern.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package i18n
+
+import "regexp"
+
+// ru holds the Russian translations of the error and validation messages.
+var ru = &catalog{
+	messages: map[string]string{
+		// Error responses
+		"the server encountered a problem and could not process your request":              "на сервере возникла проблема, и он не смог обработать запрос",
+		"the requested resource could not be found":                                        "запрошенный ресурс не найден",
+		"unable to update the record due to an edit conflict, please try again":            "не удалось обновить запись из-за одновременного изменения, попробуйте еще раз",
+		"invalid authentication credentials":                                               "неверные учетные данные",
+		"invalid or missing authentication token":                                          "токен аутентификации отсутствует или недействителен",
+		"you must be authenticated to access this resource":                                "для доступа к ресурсу необходимо войти в систему",
+		"your user account must be activated to access this resource":                      "для доступа к ресурсу учетная запись должна быть активирована",
+		"your user account doesn't have the necessary permissions to access this resource": "у вашей учетной записи нет прав для доступа к ресурсу",
+		"too many failed login attempts, please try again later":                           "слишком много неудачных попыток входа, попробуйте позже",
+		"rate limit exceeded":                                "превышен лимит запросов",
+		"this impersonation session is read-only":            "этот сеанс входа от имени пользователя доступен только для чтения",
+		"No more books available in stock.":                  "Книг больше нет в наличии.",
+		"body contains badly-formed JSON":                    "тело запроса содержит некорректный JSON",
+		"body must only contain a single JSON value":         "тело запроса должно содержать только одно значение JSON",
+		"body must not be empty":                             "тело запроса не должно быть пустым",
+		"you cannot impersonate yourself":                    "нельзя войти от имени самого себя",
+		"the scheduled price has already finished":           "запланированная цена уже перестала действовать",
+		"Book ID is required":                                "необходимо указать ID книги",
+		"Invalid Book ID":                                    "некорректный ID книги",
+		"Invalid book ID":                                    "некорректный ID книги",
+		"Book not found":                                     "Книга не найдена",
+		"Invalid request payload":                            "некорректное тело запроса",
+		"500 Internal Server Error":                          "500 Внутренняя ошибка сервера",
+		"Rating must be between 1 and 5.":                    "Оценка должна быть от 1 до 5.",
+		"You do not have permission to delete this comment.": "У вас нет прав на удаление этого комментария.",
+
+		// Validation messages
+		"must be provided":                                         "обязательное поле",
+		"must not be negative":                                     "не должно быть отрицательным",
+		"must be greater than zero":                                "должно быть больше нуля",
+		"must be greater than 0":                                   "должно быть больше нуля",
+		"must not contain duplicate values":                        "не должно содержать повторяющихся значений",
+		"must not be in the future":                                "не должно быть в будущем",
+		"must be in the future":                                    "должно быть в будущем",
+		"must be after starts_at":                                  "должно быть позже starts_at",
+		"must be a valid ISBN-10 or ISBN-13":                       "должно быть корректным ISBN-10 или ISBN-13",
+		"must be a valid email address":                            "должно быть корректным адресом электронной почты",
+		"must be an integer value":                                 "должно быть целым числом",
+		"must be a float value":                                    "должно быть числом",
+		"must be a decimal amount with at most two decimal places": "должно быть суммой не более чем с двумя знаками после точки",
+		"must have at most two decimal places":                     "должно иметь не больше двух знаков после точки",
+		"must be a comma-separated list of ids":                    "должно быть списком ID через запятую",
+		"must be a valid category id":                              "должно быть корректным ID категории",
+		"must contain valid ids":                                   "должно содержать корректные ID",
+		"must contain valid author ids":                            "должно содержать корректные ID авторов",
+		"must contain valid category ids":                          "должно содержать корректные ID категорий",
+		"must be between 1 and 100":                                "должно быть от 1 до 100",
+		"must be less than 10000":                                  "должно быть меньше 10000",
+		"must be a maximum of 10 million":                          "должно быть не больше 10 миллионов",
+		"must be 26 bytes long":                                    "должно быть длиной 26 байт",
+		"must be percent or fixed":                                 "должно быть percent или fixed",
+		"must be 1 for the base currency":                          "для базовой валюты должно быть равно 1",
+		"must be a three-letter ISO 4217 currency code":            "должно быть трехбуквенным кодом валюты ISO 4217",
+		"must be an ISO 639 language code, such as en or ru":       "должно быть кодом языка ISO 639, например en или ru",
+		"must be 3 to 32 letters, digits, hyphens or underscores":  "должно содержать от 3 до 32 латинских букв, цифр, дефисов или подчеркиваний",
+		"must be a decimal number with at most 10 digits before and 8 after the point": "должно быть десятичным числом, не более 10 цифр до точки и 8 после",
+		"must not be more than 100":                                   "должно быть не больше 100",
+		"must be less than 100000000":                                 "должно быть меньше 100000000",
+		"must not be set for a percent code":                          "не задается для процентного промокода",
+		"must not be set for a fixed code":                            "не задается для промокода с фиксированной суммой",
+		"must not be the category itself":                             "не должно быть самой категорией",
+		"must not be the category itself or one of its subcategories": "не должно быть самой категорией или одной из ее подкатегорий",
+		"must not be before the birth date":                           "не должно быть раньше даты рождения",
+		"must not be used together with before":                       "нельзя использовать вместе с before",
+		"must not contain the same ISBN twice":                        "не должно содержать один ISBN дважды",
+		"must not contain the same author twice with the same role":   "не должно содержать одного автора дважды в одной роли",
+		"must not contain your name or email address":                 "не должен содержать ваше имя или адрес электронной почты",
+		"must not be a repeated pattern or a simple sequence":         "не должен быть повторяющимся шаблоном или простой последовательностью",
+		"must contain at least 1 code":                                "должно содержать хотя бы один код",
+		"is too easy to guess, use more varied characters":            "слишком легко подобрать, используйте более разнообразные символы",
+		"is too common and has appeared in a data breach":             "слишком распространен и встречался в утечках данных",
+		"is incorrect":          "неверно",
+		"is not supported":      "не поддерживается",
+		"is taken from the URL": "задается в URL",
+		"is set per edition, use /api/v1/editions/{id}":  "задается для каждого издания, используйте /api/v1/editions/{id}",
+		"is already taken by another book of the series": "уже занято другой книгой серии",
+		"invalid cursor": "недействительный курсор",
+		"cursor does not match the sort order, start again from the first page":                      "курсор не соответствует сортировке, начните с первой страницы",
+		"cursor does not match the query, start again from the first page":                           "курсор не соответствует запросу, начните с первой страницы",
+		"invalid or expired activation token":                                                        "токен активации недействителен или истек",
+		"invalid or expired email change token":                                                      "токен смены адреса электронной почты недействителен или истек",
+		"role must be one of author, translator, editor or illustrator":                              "роль должна быть одной из: author, translator, editor, illustrator",
+		"format, isbn, price and stock_quantity must be set on each edition when editions are given": "если указаны издания, у каждого должны быть заданы format, isbn, price и stock_quantity",
+		"unknown category": "неизвестная категория",
+		"the order total is too large to be charged in this currency":                                    "сумма заказа слишком велика для оплаты в этой валюте",
+		"prices are too large to be shown in this currency":                                              "цены слишком велики для показа в этой валюте",
+		"the base currency cannot be deleted":                                                            "базовую валюту нельзя удалить",
+		"the category has subcategories, move or delete them first":                                      "у категории есть подкатегории, сначала переместите или удалите их",
+		"overlaps another scheduled price of this edition":                                               "пересекается с другим запланированным изменением цены этого издания",
+		"an edition with this ISBN already exists":                                                       "издание с таким ISBN уже существует",
+		"a user with this email address already exists":                                                  "пользователь с таким адресом электронной почты уже существует",
+		"a series with this name already exists":                                                         "серия с таким названием уже существует",
+		"a promo code with this code already exists":                                                     "такой промокод уже существует",
+		"a category with this name already exists at this level":                                         "категория с таким названием уже есть на этом уровне",
+		"a translation for this locale is not needed, the default locale is stored on the record itself": "перевод для этого языка не нужен, текст на языке по умолчанию хранится в самой записи",
+
+		// Promo code errors
+		"does not exist":              "не существует",
+		"is no longer active":         "больше не действует",
+		"is not valid yet":            "еще не действует",
+		"has expired":                 "истек",
+		"has reached its usage limit": "исчерпал лимит использований",
+		"has already been used the maximum number of times": "уже использован максимальное число раз",
+		"does not apply to this book":                       "не действует для этой книги",
+	},
+	rules: []rule{
+		{regexp.MustCompile(`^must not be more than (\d+) bytes long$`), "должно быть не длиннее $1 байт"},
+		{regexp.MustCompile(`^must be at least (\d+) bytes long$`), "должно быть не короче $1 байт"},
+		{regexp.MustCompile(`^must be a maximum of (\d+)$`), "должно быть не больше $1"},
+		{regexp.MustCompile(`^must be one of (.+)$`), "должно быть одним из значений: $1"},
+		{regexp.MustCompile(`^must be provided, the book is available as (.+)$`), "обязательное поле, книга доступна в форматах: $1"},
+		{regexp.MustCompile(`^must be in ([A-Z]{3})$`), "должно быть указано в $1"},
+		{regexp.MustCompile(`^must not sort by (.+) more than once$`), "нельзя сортировать по $1 больше одного раза"},
+		{regexp.MustCompile(`^invalid sort value (.+)$`), "недопустимое значение сортировки $1"},
+		{regexp.MustCompile(`^unknown facet (.+)$`), "неизвестный фасет $1"},
+		{regexp.MustCompile(`^unknown permission code (.+)$`), "неизвестный код разрешения $1"},
+		{regexp.MustCompile(`^unknown author id (\d+)$`), "неизвестный ID автора $1"},
+		{regexp.MustCompile(`^unknown category id (\d+)$`), "неизвестный ID категории $1"},
+		{regexp.MustCompile(`^requires an order of at least (.+)$`), "действует для заказа от $1"},
+		{regexp.MustCompile(`^the (\S+) method is not supported this resource$`), "метод $1 не поддерживается этим ресурсом"},
+		{regexp.MustCompile(`^body contains unknown key (.+)$`), "тело запроса содержит неизвестный ключ $1"},
+		{regexp.MustCompile(`^body contains incorrect JSON type for field (.+)$`), "тело запроса содержит значение неверного типа в поле $1"},
+		{regexp.MustCompile(`^body must not be larger than (\d+) bytes$`), "тело запроса не должно быть больше $1 байт"},
+		{regexp.MustCompile(`^body contains badly-formed JSON at \(charcter (\d+)\)$`), "тело запроса содержит некорректный JSON (символ $1)"},
+		{regexp.MustCompile(`^body contains incorrect JSON type \(at character (\d+)\)$`), "тело запроса содержит значение неверного типа (символ $1)"},
+
+		// Filter expression errors, see filterexpr.Error
+		{regexp.MustCompile(`^at position (\d+): expression must not be more than (\d+) bytes long$`), "позиция $1: выражение должно быть не длиннее $2 байт"},
+		{regexp.MustCompile(`^at position (\d+): expression must not have more than (\d+) comparisons$`), "позиция $1: выражение должно содержать не больше $2 сравнений"},
+		{regexp.MustCompile(`^at position (\d+): expression is nested too deeply$`), "позиция $1: выражение слишком глубоко вложено"},
+		{regexp.MustCompile(`^at position (\d+): unterminated string$`), "позиция $1: незакрытая строка"},
+		{regexp.MustCompile(`^at position (\d+): unexpected "!", use "not", "!=" or "!~"$`), `позиция $1: неожиданный "!", используйте "not", "!=" или "!~"`},
+		{regexp.MustCompile(`^at position (\d+): unexpected character (.+)$`), "позиция $1: недопустимый символ $2"},
+		{regexp.MustCompile(`^at position (\d+): unexpected end of expression$`), "позиция $1: неожиданный конец выражения"},
+		{regexp.MustCompile(`^at position (\d+): unexpected (.+)$`), "позиция $1: неожиданный элемент $2"},
+		{regexp.MustCompile(`^at position (\d+): unknown field (.+), expected one of (.+)$`), "позиция $1: неизвестное поле $2, допустимые поля: $3"},
+		{regexp.MustCompile(`^at position (\d+): operator (.+) cannot be used with field (.+)$`), "позиция $1: оператор $2 нельзя использовать с полем $3"},
+		{regexp.MustCompile(`^at position (\d+): expected "\)" but found end of expression$`), `позиция $1: ожидалось ")", но выражение закончилось`},
+		{regexp.MustCompile(`^at position (\d+): expected "\)" but found (.+)$`), `позиция $1: ожидалось ")", найдено $2`},
+		{regexp.MustCompile(`^at position (\d+): expected a field name but found end of expression$`), "позиция $1: ожидалось имя поля, но выражение закончилось"},
+		{regexp.MustCompile(`^at position (\d+): expected a field name but found (.+)$`), "позиция $1: ожидалось имя поля, найдено $2"},
+		{regexp.MustCompile(`^at position (\d+): expected an operator but found end of expression$`), "позиция $1: ожидался оператор, но выражение закончилось"},
+		{regexp.MustCompile(`^at position (\d+): expected an operator but found (.+)$`), "позиция $1: ожидался оператор, найдено $2"},
+		{regexp.MustCompile(`^at position (\d+): expected a number but found end of expression$`), "позиция $1: ожидалось число, но выражение закончилось"},
+		{regexp.MustCompile(`^at position (\d+): expected a number but found (.+)$`), "позиция $1: ожидалось число, найдено $2"},
+		{regexp.MustCompile(`^at position (\d+): expected a quoted string but found end of expression$`), "позиция $1: ожидалась строка в кавычках, но выражение закончилось"},
+		{regexp.MustCompile(`^at position (\d+): expected a quoted string but found (.+)$`), "позиция $1: ожидалась строка в кавычках, найдено $2"},
+		{regexp.MustCompile(`^at position (\d+): expected a date such as "2006-01-02" but found end of expression$`), `позиция $1: ожидалась дата, например "2006-01-02", но выражение закончилось`},
+		{regexp.MustCompile(`^at position (\d+): expected a date such as "2006-01-02" but found (.+)$`), `позиция $1: ожидалась дата, например "2006-01-02", найдено $2`},
+		{regexp.MustCompile(`^at position (\d+): expected true or false but found end of expression$`), "позиция $1: ожидалось true или false, но выражение закончилось"},
+		{regexp.MustCompile(`^at position (\d+): expected true or false but found (.+)$`), "позиция $1: ожидалось true или false, найдено $2"},
+	},
+}
@@ -0,0 +1,2 @@
+DELETE FROM permissions WHERE code = 'users:unlock';
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+                                              id bigserial PRIMARY KEY,
+                                              email citext NOT NULL,
+                                              ip text NOT NULL,
+                                              created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_email_idx ON login_attempts (email, created_at);
+CREATE INDEX IF NOT EXISTS login_attempts_ip_idx ON login_attempts (ip, created_at);
+
+-- Permission for staff allowed to lift an account lockout.
+INSERT INTO
+    permissions (code)
+VALUES
+    ('users:unlock');
@@ -0,0 +1,4 @@
+create table if not exists email_changes(
+    token_hash bytea primary key references tokens(hash) on DELETE cascade,
+    email citext not null
+)
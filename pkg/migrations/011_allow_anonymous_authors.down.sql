@@ -0,0 +1,4 @@
+DELETE FROM comments WHERE user_id IS NULL;
+DELETE FROM ratings WHERE user_id IS NULL;
+ALTER TABLE comments ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE ratings ALTER COLUMN user_id SET NOT NULL;
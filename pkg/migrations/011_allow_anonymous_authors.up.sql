@@ -0,0 +1,4 @@
+-- Comments and ratings outlive deleted accounts: their user_id is cleared instead, so that the
+-- content and the aggregates in books.avg_rating stay intact.
+ALTER TABLE comments ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE ratings ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,5 @@
+INSERT INTO
+    permissions (code)
+VALUES
+    ('users:permissions'),
+    ('metrics:read');
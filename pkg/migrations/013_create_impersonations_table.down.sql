@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS impersonation_audit;
+DROP TABLE IF EXISTS impersonations;
+DELETE FROM permissions WHERE code = 'users:impersonate';
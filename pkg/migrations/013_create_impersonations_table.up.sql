@@ -0,0 +1,27 @@
+INSERT INTO
+    permissions (code)
+VALUES
+    ('users:impersonate');
+
+-- One row per impersonation token. The token itself lives in the tokens table and the row goes
+-- away together with it.
+CREATE TABLE IF NOT EXISTS impersonations (
+                                              token_hash bytea PRIMARY KEY REFERENCES tokens(hash) ON DELETE CASCADE,
+                                              staff_user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+                                              read_only bool NOT NULL DEFAULT true,
+                                              created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+-- Every request made under impersonation. Kept after the token expires and after either account
+-- is deleted, so the user IDs are not foreign keys.
+CREATE TABLE IF NOT EXISTS impersonation_audit (
+                                                   id bigserial PRIMARY KEY,
+                                                   staff_user_id bigint NOT NULL,
+                                                   target_user_id bigint NOT NULL,
+                                                   method text NOT NULL,
+                                                   path text NOT NULL,
+                                                   status integer NOT NULL,
+                                                   created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS impersonation_audit_staff_idx ON impersonation_audit (staff_user_id, created_at);
@@ -0,0 +1,8 @@
+ALTER TABLE books
+    DROP CONSTRAINT IF EXISTS books_isbn_key,
+    DROP COLUMN IF EXISTS isbn,
+    DROP COLUMN IF EXISTS publisher,
+    DROP COLUMN IF EXISTS publication_date,
+    DROP COLUMN IF EXISTS language,
+    DROP COLUMN IF EXISTS page_count,
+    DROP COLUMN IF EXISTS description;
@@ -0,0 +1,8 @@
+ALTER TABLE books
+    ADD COLUMN isbn VARCHAR(13),
+    ADD COLUMN publisher TEXT NOT NULL DEFAULT '',
+    ADD COLUMN publication_date DATE,
+    ADD COLUMN language VARCHAR(3) NOT NULL DEFAULT '',
+    ADD COLUMN page_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD CONSTRAINT books_isbn_key UNIQUE (isbn);
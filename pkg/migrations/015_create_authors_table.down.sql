@@ -0,0 +1,3 @@
+DROP FUNCTION IF EXISTS merge_authors(bigint, bigint);
+DROP TABLE IF EXISTS book_authors;
+DROP TABLE IF EXISTS authors;
@@ -0,0 +1,65 @@
+CREATE TABLE IF NOT EXISTS authors (
+    id bigserial PRIMARY KEY,
+    name TEXT NOT NULL,
+    bio TEXT NOT NULL DEFAULT '',
+    birth_date DATE,
+    death_date DATE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS authors_name_idx ON authors (LOWER(name));
+
+CREATE TABLE IF NOT EXISTS book_authors (
+    book_id INTEGER NOT NULL REFERENCES books ON DELETE CASCADE,
+    author_id bigint NOT NULL REFERENCES authors ON DELETE CASCADE,
+    role TEXT NOT NULL DEFAULT 'author'
+        CHECK (role IN ('author', 'translator', 'editor', 'illustrator')),
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (book_id, author_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS book_authors_author_id_idx ON book_authors (author_id);
+
+-- books.author holds the whole author list as typed, e.g. "Ilf, Petrov" or "Strugatsky & Strugatsky".
+-- It is split on the separators used in the catalog (",", ";", "&" and " and "), each name
+-- becomes one author (names differing only in case are the same author), and the book is
+-- linked to its authors in the order they were listed. books.author is kept as the display
+-- form of the author list.
+CREATE TEMPORARY TABLE book_author_names AS
+SELECT books.id AS book_id, TRIM(part.name) AS name, part.position - 1 AS position
+FROM books
+CROSS JOIN LATERAL regexp_split_to_table(books.author, '\s*(?:[,;&]|\s+and\s+)\s*', 'i')
+    WITH ORDINALITY AS part(name, position)
+WHERE TRIM(part.name) <> '';
+
+INSERT INTO authors (name)
+SELECT DISTINCT ON (LOWER(name)) name
+FROM book_author_names
+ORDER BY LOWER(name), name;
+
+INSERT INTO book_authors (book_id, author_id, role, position)
+SELECT book_author_names.book_id, authors.id, 'author', MIN(book_author_names.position)
+FROM book_author_names
+INNER JOIN authors ON LOWER(authors.name) = LOWER(book_author_names.name)
+GROUP BY book_author_names.book_id, authors.id;
+
+DROP TABLE book_author_names;
+
+-- Splitting can't tell that two different spellings name one person ("Tolstoy" and
+-- "Leo Tolstoy"), or that a comma was part of a name. Such authors are cleaned up by hand
+-- after the migration: SELECT merge_authors(<duplicate id>, <kept id>) moves the books of the
+-- duplicate to the kept author and deletes the duplicate; a wrongly split name is fixed by
+-- renaming one part and merging the other into it.
+CREATE OR REPLACE FUNCTION merge_authors(from_id bigint, into_id bigint) RETURNS void AS $$
+BEGIN
+    INSERT INTO book_authors (book_id, author_id, role, position)
+    SELECT book_id, into_id, role, position
+    FROM book_authors
+    WHERE author_id = from_id
+    ON CONFLICT DO NOTHING;
+
+    DELETE FROM authors WHERE id = from_id;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id bigserial PRIMARY KEY,
+    parent_id bigint REFERENCES categories ON DELETE RESTRICT,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+-- Sibling categories must have distinct names. Top level categories have a NULL parent, which
+-- a plain UNIQUE constraint would treat as distinct.
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_name_key ON categories (COALESCE(parent_id, 0), LOWER(name));
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+
+CREATE TABLE IF NOT EXISTS book_categories (
+    book_id INTEGER NOT NULL REFERENCES books ON DELETE CASCADE,
+    category_id bigint NOT NULL REFERENCES categories ON DELETE CASCADE,
+    PRIMARY KEY (book_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_categories_category_id_idx ON book_categories (category_id);
@@ -0,0 +1,14 @@
+-- The search vector holds both English and Russian stems of every field, so that a query in
+-- either language matches regardless of the language of the book. Title matches rank above
+-- author matches, which rank above description matches.
+ALTER TABLE books
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(author, '')), 'B') ||
+        setweight(to_tsvector('russian', COALESCE(author, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'C') ||
+        setweight(to_tsvector('russian', COALESCE(description, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS books_search_vector_idx ON books USING GIN (search_vector);
@@ -0,0 +1,36 @@
+ALTER TABLE purchases DROP COLUMN IF EXISTS edition_id;
+
+-- The ISBN of each book's first edition with one moves back to books, and the lowest price
+-- and total stock of its editions become the price and stock of the book.
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS isbn VARCHAR(13),
+    ADD COLUMN IF NOT EXISTS price int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS stock_quantity INTEGER NOT NULL DEFAULT 0;
+
+UPDATE books
+SET price = totals.price,
+    stock_quantity = totals.stock_quantity
+FROM (
+    SELECT book_id, ROUND(MIN(price)) AS price, SUM(stock_quantity) AS stock_quantity
+    FROM editions
+    GROUP BY book_id
+) AS totals
+WHERE totals.book_id = books.id;
+
+ALTER TABLE books
+    ALTER COLUMN price DROP DEFAULT,
+    ALTER COLUMN stock_quantity DROP DEFAULT;
+
+UPDATE books
+SET isbn = first.isbn
+FROM (
+    SELECT DISTINCT ON (book_id) book_id, isbn
+    FROM editions
+    WHERE isbn IS NOT NULL
+    ORDER BY book_id, id
+) AS first
+WHERE first.book_id = books.id;
+
+ALTER TABLE books ADD CONSTRAINT books_isbn_key UNIQUE (isbn);
+
+DROP TABLE IF EXISTS editions;
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS editions (
+    id bigserial PRIMARY KEY,
+    book_id INTEGER NOT NULL REFERENCES books ON DELETE CASCADE,
+    format TEXT NOT NULL
+        CHECK (format IN ('hardcover', 'paperback', 'ebook', 'audiobook')),
+    isbn VARCHAR(13),
+    price NUMERIC(10, 2) NOT NULL CHECK (price >= 0),
+    stock_quantity INTEGER NOT NULL DEFAULT 0 CHECK (stock_quantity >= 0),
+    page_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1,
+    CONSTRAINT editions_isbn_key UNIQUE (isbn)
+);
+
+CREATE INDEX IF NOT EXISTS editions_book_id_idx ON editions (book_id);
+
+-- Every existing book becomes a work with a single paperback edition that takes over its
+-- ISBN, price and stock. Prices are now kept per edition with cents. The ISBN identifies an
+-- edition, so it is dropped from books; the price and stock of a work are the lowest price
+-- and the total stock of its editions, computed when the book is read, so they are dropped
+-- as well.
+INSERT INTO editions (book_id, format, isbn, price, stock_quantity, page_count)
+SELECT id, 'paperback', isbn, price, stock_quantity, page_count
+FROM books;
+
+ALTER TABLE books
+    DROP CONSTRAINT IF EXISTS books_isbn_key,
+    DROP COLUMN IF EXISTS isbn,
+    DROP COLUMN IF EXISTS price,
+    DROP COLUMN IF EXISTS stock_quantity;
+
+-- A purchase is of a particular edition. Existing purchases belong to the only edition
+-- their book has at this point.
+ALTER TABLE purchases ADD COLUMN edition_id bigint REFERENCES editions ON DELETE SET NULL;
+
+UPDATE purchases
+SET edition_id = editions.id
+FROM editions
+WHERE editions.book_id = purchases.book_id;
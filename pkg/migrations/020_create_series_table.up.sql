@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS series (
+    id bigserial PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS series_name_key ON series (LOWER(name));
+
+-- Positions are numeric so that novellas can be placed between volumes, e.g. 2.5. A book may
+-- belong to several series, such as a sub-series and the larger cycle it is part of.
+CREATE TABLE IF NOT EXISTS book_series (
+    book_id INTEGER NOT NULL REFERENCES books ON DELETE CASCADE,
+    series_id bigint NOT NULL REFERENCES series ON DELETE CASCADE,
+    position NUMERIC(6, 2) NOT NULL CHECK (position > 0),
+    PRIMARY KEY (book_id, series_id),
+    CONSTRAINT book_series_position_key UNIQUE (series_id, position)
+);
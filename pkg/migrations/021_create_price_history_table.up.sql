@@ -0,0 +1,50 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- Scheduled price changes of an edition. A change with an end is a sale: when it ends, the
+-- price it replaced (restore_price) comes back. A change without an end is permanent. When
+-- the edition is deleted, its changes are kept for the price history they refer to, and the
+-- price scheduler cancels those that are still pending or active.
+CREATE TABLE IF NOT EXISTS scheduled_prices (
+    id bigserial PRIMARY KEY,
+    edition_id bigint REFERENCES editions ON DELETE SET NULL,
+    price NUMERIC(10, 2) NOT NULL CHECK (price >= 0),
+    starts_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP(0) WITH TIME ZONE CHECK (ends_at > starts_at),
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'active', 'completed', 'cancelled', 'missed')),
+    restore_price NUMERIC(10, 2),
+    created_by bigint REFERENCES users ON DELETE SET NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- Two upcoming or running changes of the same edition must not overlap.
+    CONSTRAINT scheduled_prices_no_overlap EXCLUDE USING gist (
+        edition_id WITH =,
+        tstzrange(starts_at, ends_at) WITH &&
+    ) WHERE (status IN ('pending', 'active'))
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_prices_pending_idx ON scheduled_prices (starts_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS scheduled_prices_active_idx ON scheduled_prices (ends_at) WHERE status = 'active';
+
+-- Every price an edition has had. old_price is NULL for the price an edition was created
+-- with. changed_by is the user who changed the price or scheduled the change, if known.
+-- The history outlives the edition: edition_id is not a foreign key, and the book and format
+-- of the edition are copied into each row, so the history of a book still shows the prices
+-- of its deleted editions. It is only removed together with the book.
+CREATE TABLE IF NOT EXISTS price_history (
+    id bigserial PRIMARY KEY,
+    edition_id bigint NOT NULL,
+    book_id INTEGER NOT NULL REFERENCES books ON DELETE CASCADE,
+    format TEXT NOT NULL,
+    old_price NUMERIC(10, 2),
+    new_price NUMERIC(10, 2) NOT NULL,
+    changed_by bigint REFERENCES users ON DELETE SET NULL,
+    scheduled_price_id bigint REFERENCES scheduled_prices ON DELETE SET NULL,
+    changed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS price_history_book_id_idx ON price_history (book_id, changed_at);
+
+-- The current price of every existing edition starts its history.
+INSERT INTO price_history (edition_id, book_id, format, new_price, changed_at)
+SELECT id, book_id, format, price, created_at
+FROM editions;
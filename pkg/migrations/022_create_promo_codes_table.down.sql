@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE code = 'promo_codes:write';
+
+ALTER TABLE purchases
+    DROP COLUMN IF EXISTS promo_code_id,
+    DROP COLUMN IF EXISTS discount;
+
+DROP TABLE IF EXISTS promo_codes;
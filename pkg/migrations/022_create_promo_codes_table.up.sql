@@ -0,0 +1,35 @@
+-- A promo code takes a percentage or a fixed amount off an order. Restrictions are lists of
+-- book, author and category ids; a code with none of them applies to every book, otherwise to
+-- books matching any of them. Categories include their subcategories.
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id bigserial PRIMARY KEY,
+    code citext NOT NULL UNIQUE,
+    kind TEXT NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    value NUMERIC(10, 2) NOT NULL CHECK (value > 0),
+    starts_at TIMESTAMP(0) WITH TIME ZONE,
+    ends_at TIMESTAMP(0) WITH TIME ZONE,
+    max_uses INTEGER NOT NULL DEFAULT 0,
+    max_uses_per_user INTEGER NOT NULL DEFAULT 0,
+    min_order NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    book_ids bigint[] NOT NULL DEFAULT '{}',
+    author_ids bigint[] NOT NULL DEFAULT '{}',
+    category_ids bigint[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    CHECK (kind <> 'percent' OR value <= 100),
+    CHECK (ends_at > starts_at)
+);
+
+-- total_price is what was charged, after the discount.
+ALTER TABLE purchases
+    ADD COLUMN discount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN promo_code_id bigint REFERENCES promo_codes ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS purchases_promo_code_id_idx ON purchases (promo_code_id, user_id);
+
+INSERT INTO
+    permissions (code)
+VALUES
+    ('promo_codes:write');
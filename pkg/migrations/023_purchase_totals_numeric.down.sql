@@ -0,0 +1,6 @@
+ALTER TABLE purchases
+    DROP CONSTRAINT IF EXISTS purchases_total_price_check,
+    ALTER COLUMN discount TYPE NUMERIC(10, 2),
+    ALTER COLUMN total_price DROP NOT NULL,
+    ALTER COLUMN total_price DROP DEFAULT,
+    ALTER COLUMN total_price TYPE DECIMAL(10, 2);
@@ -0,0 +1,16 @@
+-- Purchase totals are read into an exact money type, so they can no longer be NULL. Older
+-- rows without a total are priced from their edition where it still exists.
+UPDATE purchases
+SET total_price = COALESCE(
+    (SELECT editions.price * purchases.quantity FROM editions WHERE editions.id = purchases.edition_id),
+    0
+)
+WHERE total_price IS NULL;
+
+-- A total is the edition price times up to 100 copies, which does not fit NUMERIC(10, 2).
+ALTER TABLE purchases
+    ALTER COLUMN total_price TYPE NUMERIC(12, 2),
+    ALTER COLUMN total_price SET DEFAULT 0,
+    ALTER COLUMN total_price SET NOT NULL,
+    ALTER COLUMN discount TYPE NUMERIC(12, 2),
+    ADD CONSTRAINT purchases_total_price_check CHECK (total_price >= 0);
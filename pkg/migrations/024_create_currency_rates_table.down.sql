@@ -0,0 +1,10 @@
+DELETE FROM permissions WHERE code = 'currencies:write';
+
+ALTER TABLE purchases
+    DROP COLUMN IF EXISTS exchange_rate,
+    DROP COLUMN IF EXISTS charged_total,
+    DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE users DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS currency_rates;
@@ -0,0 +1,35 @@
+-- Exchange rates for showing and charging prices in other currencies. Prices are stored in
+-- USD; rate is the number of units of currency per one USD. Converted amounts are rounded
+-- to a multiple of rounding_step in the direction given by rounding_mode.
+CREATE TABLE IF NOT EXISTS currency_rates (
+    currency CHAR(3) PRIMARY KEY CHECK (currency ~ '^[A-Z]{3}$'),
+    rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+    rounding_step NUMERIC(10, 2) NOT NULL DEFAULT 0.01 CHECK (rounding_step > 0),
+    rounding_mode text NOT NULL DEFAULT 'nearest' CHECK (rounding_mode IN ('nearest', 'up', 'down')),
+    updated_by bigint REFERENCES users ON DELETE SET NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    CHECK (currency <> 'USD' OR rate = 1)
+);
+
+INSERT INTO currency_rates (currency, rate) VALUES ('USD', 1) ON CONFLICT DO NOTHING;
+
+-- The currency a user wants to see prices in, NULL for USD.
+ALTER TABLE users
+    ADD COLUMN currency CHAR(3) REFERENCES currency_rates ON DELETE SET NULL;
+
+-- total_price stays in USD. The customer is charged charged_total in currency, converted at
+-- exchange_rate at the time of purchase.
+ALTER TABLE purchases
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN charged_total NUMERIC(14, 2),
+    ADD COLUMN exchange_rate NUMERIC(18, 8) NOT NULL DEFAULT 1;
+
+UPDATE purchases SET charged_total = total_price;
+
+ALTER TABLE purchases ALTER COLUMN charged_total SET NOT NULL;
+
+INSERT INTO
+    permissions (code)
+VALUES
+    ('currencies:write');
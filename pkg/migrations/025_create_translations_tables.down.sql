@@ -0,0 +1,22 @@
+DROP TRIGGER IF EXISTS books_refresh_search_vector ON books;
+DROP FUNCTION IF EXISTS books_refresh_search_vector();
+
+DROP TABLE IF EXISTS category_translations;
+DROP TABLE IF EXISTS book_translations;
+DROP FUNCTION IF EXISTS book_translations_refresh_search_vector();
+
+-- search_vector goes back to a generated column of the texts of the book itself.
+ALTER TABLE books DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS book_search_vector(INTEGER, text, text, text);
+
+ALTER TABLE books
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(author, '')), 'B') ||
+        setweight(to_tsvector('russian', COALESCE(author, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'C') ||
+        setweight(to_tsvector('russian', COALESCE(description, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS books_search_vector_idx ON books USING GIN (search_vector);
@@ -0,0 +1,82 @@
+-- Translations of book and category texts. The columns of books and categories hold the
+-- texts in the default locale; a translation replaces them for readers in its locale.
+CREATE TABLE IF NOT EXISTS book_translations (
+    book_id INTEGER NOT NULL REFERENCES books ON DELETE CASCADE,
+    locale text NOT NULL CHECK (locale ~ '^[a-z]{2,3}$'),
+    title text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    PRIMARY KEY (book_id, locale)
+);
+
+CREATE TABLE IF NOT EXISTS category_translations (
+    category_id bigint NOT NULL REFERENCES categories ON DELETE CASCADE,
+    locale text NOT NULL CHECK (locale ~ '^[a-z]{2,3}$'),
+    name text NOT NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    PRIMARY KEY (category_id, locale)
+);
+
+-- Full-text search also finds books by their translated titles and descriptions, with the
+-- same weights as the texts of the book itself. A generated column can only use its own row,
+-- so search_vector becomes a plain column kept up to date by triggers on books and
+-- book_translations.
+CREATE OR REPLACE FUNCTION book_search_vector(work_id INTEGER, title text, author text, description text)
+RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(author, '')), 'B') ||
+        setweight(to_tsvector('russian', COALESCE(author, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'C') ||
+        setweight(to_tsvector('russian', COALESCE(description, '')), 'C') ||
+        COALESCE((
+            SELECT setweight(to_tsvector('english', string_agg(t.title, ' ')), 'A') ||
+                setweight(to_tsvector('russian', string_agg(t.title, ' ')), 'A') ||
+                setweight(to_tsvector('english', string_agg(t.description, ' ')), 'C') ||
+                setweight(to_tsvector('russian', string_agg(t.description, ' ')), 'C')
+            FROM book_translations t
+            WHERE t.book_id = work_id
+        ), ''::tsvector)
+$$ LANGUAGE sql STABLE;
+
+ALTER TABLE books DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE books ADD COLUMN search_vector tsvector;
+
+UPDATE books
+SET search_vector = book_search_vector(id, title, author, description);
+
+CREATE INDEX IF NOT EXISTS books_search_vector_idx ON books USING GIN (search_vector);
+
+CREATE OR REPLACE FUNCTION books_refresh_search_vector() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := book_search_vector(NEW.id, NEW.title, NEW.author, NEW.description);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER books_refresh_search_vector
+    BEFORE INSERT OR UPDATE OF title, author, description ON books
+    FOR EACH ROW EXECUTE FUNCTION books_refresh_search_vector();
+
+CREATE OR REPLACE FUNCTION book_translations_refresh_search_vector() RETURNS trigger AS $$
+DECLARE
+    work_id INTEGER;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        work_id := OLD.book_id;
+    ELSE
+        work_id := NEW.book_id;
+    END IF;
+
+    UPDATE books
+    SET search_vector = book_search_vector(id, title, author, description)
+    WHERE id = work_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER book_translations_refresh_search_vector
+    AFTER INSERT OR UPDATE OR DELETE ON book_translations
+    FOR EACH ROW EXECUTE FUNCTION book_translations_refresh_search_vector();
@@ -0,0 +1,309 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+	"github.com/lib/pq"
+)
+
+// AuthorRoles перечисляет допустимые роли участника в создании книги
+var AuthorRoles = []string{"author", "translator", "editor", "illustrator"}
+
+// Author представляет автора, переводчика, редактора или иллюстратора
+type Author struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio"`
+	BirthDate Date      `json:"birth_date"`
+	DeathDate Date      `json:"death_date"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int32     `json:"version"`
+}
+
+// BookAuthor описывает участие автора в книге: роль и порядок в списке авторов
+type BookAuthor struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Position int    `json:"position"`
+}
+
+// AuthorModel обрабатывает операции с авторами в базе данных
+type AuthorModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// authorSortColumns сопоставляет значения параметра sort списка авторов выражениям SQL
+var authorSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "LOWER(name)",
+	"birth_date": "birth_date",
+}
+
+// Insert добавляет нового автора
+func (m AuthorModel) Insert(author *Author) error {
+	query := `
+        INSERT INTO authors (name, bio, birth_date, death_date)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, updated_at, version
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{author.Name, author.Bio, author.BirthDate, author.DeathDate}
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&author.ID, &author.CreatedAt, &author.UpdatedAt, &author.Version)
+}
+
+// Get возвращает автора по ID
+func (m AuthorModel) Get(id int64) (*Author, error) {
+	query := `
+    SELECT id, name, bio, birth_date, death_date, created_at, updated_at, version
+    FROM authors
+    WHERE id = $1
+    `
+	var author Author
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&author.ID, &author.Name, &author.Bio, &author.BirthDate, &author.DeathDate,
+		&author.CreatedAt, &author.UpdatedAt, &author.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &author, nil
+}
+
+// GetAll возвращает авторов, имя которых содержит name, с учетом пагинации и сортировки
+func (m AuthorModel) GetAll(name string, filters Filters) ([]*Author, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, name, bio, birth_date, death_date, created_at, updated_at, version
+        FROM authors
+        WHERE (LOWER(name) LIKE LOWER($1) OR $1 = '')
+        %s
+        LIMIT $2 OFFSET $3
+        `, filters.orderBy(authorSortColumns))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, "%"+name+"%", filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	authors := []*Author{}
+	for rows.Next() {
+		var author Author
+		err := rows.Scan(
+			&totalRecords, &author.ID, &author.Name, &author.Bio, &author.BirthDate, &author.DeathDate,
+			&author.CreatedAt, &author.UpdatedAt, &author.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		authors = append(authors, &author)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return authors, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// GetByIDs возвращает авторов с указанными ID. Отсутствующие ID в результат не попадают.
+func (m AuthorModel) GetByIDs(ids []int64) (map[int64]*Author, error) {
+	query := `
+    SELECT id, name, bio, birth_date, death_date, created_at, updated_at, version
+    FROM authors
+    WHERE id = ANY($1)
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	authors := make(map[int64]*Author)
+	for rows.Next() {
+		var author Author
+		err := rows.Scan(
+			&author.ID, &author.Name, &author.Bio, &author.BirthDate, &author.DeathDate,
+			&author.CreatedAt, &author.UpdatedAt, &author.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		authors[author.ID] = &author
+	}
+	return authors, rows.Err()
+}
+
+// Update сохраняет изменения автора. Если запись была изменена или удалена с момента
+// чтения, возвращается ErrEditConflict.
+func (m AuthorModel) Update(author *Author) error {
+	query := `
+    UPDATE authors
+    SET name = $1, bio = $2, birth_date = $3, death_date = $4, updated_at = NOW(), version = version + 1
+    WHERE id = $5 AND version = $6
+    RETURNING updated_at, version
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{author.Name, author.Bio, author.BirthDate, author.DeathDate, author.ID, author.Version}
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&author.UpdatedAt, &author.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete удаляет автора вместе с его связями с книгами. Строка books.author, отображаемая
+// для этих книг, не меняется.
+func (m AuthorModel) Delete(id int64) error {
+	query := `
+    DELETE FROM authors
+    WHERE id = $1
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// loadBookAuthors заполняет поле Authors у переданных книг одним запросом
+func loadBookAuthors(ctx context.Context, db *sql.DB, books ...*Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(books))
+	byID := make(map[int64]*Book, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+		byID[book.ID] = book
+	}
+
+	query := `
+    SELECT book_authors.book_id, authors.id, authors.name, book_authors.role, book_authors.position
+    FROM book_authors
+    INNER JOIN authors ON authors.id = book_authors.author_id
+    WHERE book_authors.book_id = ANY($1)
+    ORDER BY book_authors.book_id, book_authors.position, authors.name
+    `
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			bookID int64
+			author BookAuthor
+		)
+		err := rows.Scan(&bookID, &author.ID, &author.Name, &author.Role, &author.Position)
+		if err != nil {
+			return err
+		}
+		if book := byID[bookID]; book != nil {
+			book.Authors = append(book.Authors, &author)
+		}
+	}
+	return rows.Err()
+}
+
+// setBookAuthors заменяет список авторов книги. Позиция автора определяется его порядком
+// в списке.
+func setBookAuthors(ctx context.Context, tx *sql.Tx, bookID int64, authors []*BookAuthor) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = $1`, bookID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+    INSERT INTO book_authors (book_id, author_id, role, position)
+    VALUES ($1, $2, $3, $4)
+    `
+	for i, author := range authors {
+		author.Position = i
+		_, err = tx.ExecContext(ctx, query, bookID, author.ID, author.Role, author.Position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AuthorDisplayName составляет строку для books.author из имен участников с ролью "author"
+func AuthorDisplayName(authors []*BookAuthor) string {
+	var names []string
+	for _, author := range authors {
+		if author.Role == "author" {
+			names = append(names, author.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// ValidateAuthor проверяет поля автора
+func ValidateAuthor(v *validator.Validator, author *Author) {
+	v.Check(strings.TrimSpace(author.Name) != "", "name", "must be provided")
+	v.Check(len(author.Name) <= 255, "name", "must not be more than 255 bytes long")
+	v.Check(len(author.Bio) <= 10_000, "bio", "must not be more than 10000 bytes long")
+	v.Check(author.BirthDate.Before(time.Now()), "birth_date", "must not be in the future")
+	v.Check(author.DeathDate.Before(time.Now()), "death_date", "must not be in the future")
+	v.Check(author.BirthDate.IsZero() || author.DeathDate.IsZero() || !author.DeathDate.Before(author.BirthDate.Time),
+		"death_date", "must not be before the birth date")
+}
+
+// validateBookAuthors проверяет роли и отсутствие повторов в списке авторов книги
+func validateBookAuthors(v *validator.Validator, authors []*BookAuthor) {
+	seen := make(map[BookAuthor]bool)
+	for _, author := range authors {
+		v.Check(author.ID > 0, "authors", "must contain valid author ids")
+		v.Check(validator.In(author.Role, AuthorRoles...), "authors", "role must be one of author, translator, editor or illustrator")
+
+		key := BookAuthor{ID: author.ID, Role: author.Role}
+		v.Check(!seen[key], "authors", "must not contain the same author twice with the same role")
+		seen[key] = true
+	}
+}
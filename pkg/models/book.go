@@ -3,22 +3,61 @@ package models
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
 	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+)
+
+var (
+	// ErrDuplicateISBN возвращается при попытке сохранить издание с уже существующим ISBN
+	ErrDuplicateISBN = errors.New("duplicate isbn")
+
+	// LanguageRX проверяет код языка ISO 639-1 или 639-2 в нижнем регистре
+	LanguageRX = regexp.MustCompile("^[a-z]{2,3}$")
 )
 
-// Book представляет модель книги
+// Book представляет модель книги как произведения. Цена и остаток на складе задаются для
+// каждого издания отдельно, а Price и StockQuantity книги — это минимальная цена и общий
+// остаток ее изданий, они вычисляются при каждом чтении книги.
 type Book struct {
-	ID            int64     `json:"id"`
-	Title         string    `json:"title"`
-	Author        string    `json:"author"`
-	Price         float64   `json:"price"`
-	StockQuantity int       `json:"stock_quantity"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	AvgRating     float64   `json:"avg_rating"`
-	RatingCount   int       `json:"rating_count"`
+	ID              int64     `json:"id"`
+	Title           string    `json:"title"`
+	Author          string    `json:"author"`
+	Price           Money     `json:"price"`
+	StockQuantity   int       `json:"stock_quantity"`
+	Publisher       string    `json:"publisher"`
+	PublicationDate Date      `json:"publication_date"`
+	Language        string    `json:"language"`
+	PageCount       int       `json:"page_count"`
+	Description     string    `json:"description"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	AvgRating       float64   `json:"avg_rating"`
+	RatingCount     int       `json:"rating_count"`
+
+	// Authors содержит авторов, переводчиков, редакторов и иллюстраторов книги. Author
+	// остается строкой для отображения. При обновлении список заменяется, только если это
+	// указано в BookUpdate.
+	Authors []*BookAuthor `json:"authors,omitempty"`
+
+	// Categories содержит категории, к которым отнесена книга. При обновлении список
+	// заменяется, только если это указано в BookUpdate.
+	Categories []*BookCategory `json:"categories,omitempty"`
+
+	// Editions содержит издания книги. При вставке книги они добавляются вместе с ней,
+	// при обновлении не меняются.
+	Editions []*Edition `json:"editions,omitempty"`
+
+	// Series содержит серии, в которые входит книга, и ее место в каждой из них
+	Series []*BookSeries `json:"series,omitempty"`
+
+	// SearchRank и Highlight заполняются только при полнотекстовом поиске
+	SearchRank float64        `json:"search_rank,omitempty"`
+	Highlight  *BookHighlight `json:"highlight,omitempty"`
 }
 
 // BookModel обрабатывает операции с книгами в базе данных
@@ -28,28 +67,66 @@ type BookModel struct {
 	ErrorLog *log.Logger
 }
 
+// bookPrice и bookStock вычисляют цену книги и ее общий остаток по изданиям. Они не хранятся в
+// books, чтобы покупка, уменьшающая остаток издания, не переписывала строку книги.
+const (
+	bookPrice = `(SELECT COALESCE(MIN(editions.price), 0) FROM editions WHERE editions.book_id = books.id)`
+	bookStock = `(SELECT COALESCE(SUM(editions.stock_quantity), 0) FROM editions WHERE editions.book_id = books.id)`
+)
+
+// bookColumns перечисляет столбцы книги в порядке, в котором их считывает scanDest
+const bookColumns = `books.id, books.created_at, books.updated_at, books.title, books.author, ` + bookPrice + `,
+        ` + bookStock + `, books.publisher, books.publication_date, books.language,
+        books.page_count, books.description, books.avg_rating, books.rating_count`
+
+// scanDest возвращает указатели на поля книги в порядке столбцов bookColumns
+func (b *Book) scanDest() []interface{} {
+	return []interface{}{
+		&b.ID, &b.CreatedAt, &b.UpdatedAt, &b.Title, &b.Author, &b.Price,
+		&b.StockQuantity, &b.Publisher, &b.PublicationDate, &b.Language,
+		&b.PageCount, &b.Description, &b.AvgRating, &b.RatingCount,
+	}
+}
+
 // GetAll возвращает все книги с учетом фильтров
-func (m BookModel) GetAll(title string, author string, priceRangeStart, priceRangeEnd, minRating float64, filters Filters) ([]*Book, Metadata, error) {
-	// Конструирование SQL-запроса с фильтрацией и сортировкой
+func (m BookModel) GetAll(q BookQuery, filters Filters) ([]*Book, Metadata, error) {
+	// Конструирование SQL-запроса с фильтрацией и сортировкой. Аргументы нумеруются в порядке
+	// добавления, поэтому rank, WHERE и LIMIT собираются до форматирования запроса.
+	var args sqlArgs
+	rank := "0"
+	if q.Search != "" {
+		rank = "ts_rank(books.search_vector, " + q.tsquery(&args) + ")"
+	}
+	columns := bookSortColumns(rank)
+
+	conditions := q.where(&args, "")
+	if keyset := filters.keyset(columns, &args); keyset != "" {
+		if conditions == "" {
+			conditions = "WHERE " + keyset
+		} else {
+			conditions += "\n        AND " + keyset
+		}
+	}
+
+	// В режиме курсоров общее количество не считается: это потребовало бы просмотра всех
+	// подходящих строк, чего курсоры и позволяют избежать
+	total := "count(*) OVER()"
+	if filters.cursorMode() {
+		total = "0"
+	}
+
 	query := fmt.Sprintf(`
-        SELECT count(*) OVER(), id, created_at, updated_at, title, author, price, stock_quantity, avg_rating, rating_count
+        SELECT %s, %s, %s AS rank
         FROM books
-        WHERE (LOWER(title) LIKE LOWER($1) OR $1 = '')
-        AND (LOWER(author) LIKE LOWER($2) OR $2 = '')
-        AND (price >= $3 OR $3 = 0)
-        AND (price <= $4 OR $4 = 0)
-        AND (avg_rating >= $5 OR $5 = 0)
-        ORDER BY %s %s, id ASC
-        LIMIT $6 OFFSET $7
-        `, filters.sortColumn(), filters.sortDirection())
+        %s
+        %s
+        %s
+        `, total, bookColumns, rank, conditions, filters.orderBy(columns), filters.pageLimit(&args))
 
 	// Создание контекста с тайм-аутом 3 секунды
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	// Подготовка аргументов для запроса
-	args := []interface{}{"%" + title + "%", "%" + author + "%", priceRangeStart, priceRangeEnd, minRating, filters.limit(), filters.offset()}
-
 	// Выполнение запроса
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -68,7 +145,8 @@ func (m BookModel) GetAll(title string, author string, priceRangeStart, priceRan
 	// Итерация по результирующему набору и сканирование каждой строки в структуру Book
 	for rows.Next() {
 		var book Book
-		err := rows.Scan(&totalRecords, &book.ID, &book.CreatedAt, &book.UpdatedAt, &book.Title, &book.Author, &book.Price, &book.StockQuantity, &book.AvgRating, &book.RatingCount)
+		dest := append([]interface{}{&totalRecords}, book.scanDest()...)
+		err := rows.Scan(append(dest, &book.SearchRank)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -80,28 +158,118 @@ func (m BookModel) GetAll(title string, author string, priceRangeStart, priceRan
 		return nil, Metadata{}, err
 	}
 
-	// Подготовка метаданных
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	// Подготовка метаданных и курсоров соседних страниц
+	books, metadata := keysetPage(filters, books, totalRecords)
+
+	// Загрузка авторов, категорий, изданий и серий для всех книг страницы
+	if err = loadBookAuthors(ctx, m.DB, books...); err != nil {
+		return nil, Metadata{}, err
+	}
+	if err = loadBookCategories(ctx, m.DB, books...); err != nil {
+		return nil, Metadata{}, err
+	}
+	if err = loadBookEditions(ctx, m.DB, books...); err != nil {
+		return nil, Metadata{}, err
+	}
+	if err = loadBookSeries(ctx, m.DB, books...); err != nil {
+		return nil, Metadata{}, err
+	}
+	if err = loadHighlights(ctx, m.DB, q.Search, books...); err != nil {
+		return nil, Metadata{}, err
+	}
 
 	return books, metadata, nil
 }
 
-// Insert вставляет новую книгу в базу данных
-func (m *BookModel) Insert(book *Book) error {
+// bookSortColumns сопоставляет значения параметра sort выражениям SQL. rank — выражение
+// релевантности полнотекстового поиска.
+func bookSortColumns(rank string) map[string]string {
+	return map[string]string{
+		"id":             "books.id",
+		"title":          "books.title",
+		"author":         "books.author",
+		"price":          bookPrice,
+		"avg_rating":     "COALESCE(books.avg_rating, 0)",
+		"stock_quantity": bookStock,
+		"page_count":     "books.page_count",
+		"rank":           rank,
+	}
+}
+
+// sortValue возвращает значение книги для ключа сортировки, используется в курсорах
+func (b *Book) sortValue(column string) interface{} {
+	switch column {
+	case "title":
+		return b.Title
+	case "author":
+		return b.Author
+	case "price":
+		return b.Price.String()
+	case "avg_rating":
+		return b.AvgRating
+	case "stock_quantity":
+		return b.StockQuantity
+	case "page_count":
+		return b.PageCount
+	case "rank":
+		return b.SearchRank
+	default:
+		return b.ID
+	}
+}
+
+// Insert вставляет новую книгу в базу данных вместе с изданиями и списками авторов и
+// категорий. Цена и остаток книги считываются после добавления изданий, начальные цены
+// изданий записываются в историю от имени пользователя createdBy (0, если неизвестен).
+func (m *BookModel) Insert(book *Book, createdBy int64) error {
 	query := `
-        INSERT INTO books (title, author, price, stock_quantity) 
-        VALUES ($1, $2, $3, $4) 
+        INSERT INTO books (title, author, publisher, publication_date, language, page_count, description) 
+        VALUES ($1, $2, $3, $4, $5, $6, $7) 
         RETURNING id, created_at, updated_at, avg_rating, rating_count
     `
-	args := []interface{}{book.Title, book.Author, book.Price, book.StockQuantity}
+	args := []interface{}{
+		book.Title, book.Author, book.Publisher, book.PublicationDate, book.Language, book.PageCount, book.Description,
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&book.ID, &book.CreatedAt, &book.UpdatedAt, &book.AvgRating, &book.RatingCount)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&book.ID, &book.CreatedAt, &book.UpdatedAt, &book.AvgRating, &book.RatingCount)
 	if err != nil {
 		m.ErrorLog.Printf("Ошибка при вставке новой книги: %v", err)
 		return err
 	}
+
+	for _, edition := range book.Editions {
+		edition.BookID = book.ID
+		if err = insertEdition(ctx, tx, edition, createdBy); err != nil {
+			return err
+		}
+	}
+	err = tx.QueryRowContext(ctx, `SELECT `+bookPrice+`, `+bookStock+` FROM books WHERE id = $1`, book.ID).Scan(&book.Price, &book.StockQuantity)
+	if err != nil {
+		return err
+	}
+
+	if book.Authors != nil {
+		if err = setBookAuthors(ctx, tx, book.ID, book.Authors); err != nil {
+			return err
+		}
+	}
+	if book.Categories != nil {
+		if err = setBookCategories(ctx, tx, book.ID, book.Categories); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
 	m.InfoLog.Printf("Книга [%s] успешно добавлена с ID %d", book.Title, book.ID)
 	return nil
 }
@@ -109,35 +277,96 @@ func (m *BookModel) Insert(book *Book) error {
 // Get возвращает книгу по ID
 func (m BookModel) Get(id int64) (*Book, error) {
 	query := `
-    SELECT id, created_at, updated_at, title, author, price, stock_quantity, avg_rating, rating_count
+    SELECT ` + bookColumns + `
     FROM books
     WHERE id = $1
     `
+	return m.getOne(query, id)
+}
+
+// getOne выполняет запрос, возвращающий одну книгу
+func (m BookModel) getOne(query string, args ...interface{}) (*Book, error) {
 	var book Book
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	row := m.DB.QueryRowContext(ctx, query, id)
-	err := row.Scan(&book.ID, &book.CreatedAt, &book.UpdatedAt, &book.Title, &book.Author, &book.Price, &book.StockQuantity, &book.AvgRating, &book.RatingCount)
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(book.scanDest()...)
 	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if err = loadBookAuthors(ctx, m.DB, &book); err != nil {
+		return nil, err
+	}
+	if err = loadBookCategories(ctx, m.DB, &book); err != nil {
+		return nil, err
+	}
+	if err = loadBookEditions(ctx, m.DB, &book); err != nil {
+		return nil, err
+	}
+	if err = loadBookSeries(ctx, m.DB, &book); err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
-// Update обновляет информацию о книге в базе данных
-func (m BookModel) Update(book *Book) error {
+// BookUpdate перечисляет списки книги, которые заменяет Update. Остальные списки в базе данных
+// не меняются, даже если в книге они пустые.
+type BookUpdate struct {
+	Authors    bool
+	Categories bool
+}
+
+// Update обновляет информацию о книге в базе данных. Списки авторов и категорий заменяются,
+// только если это указано в set. Цена и остаток меняются через издания.
+func (m BookModel) Update(book *Book, set BookUpdate) error {
 	query := `
     UPDATE books
-    SET title = $1, author = $2, price = $3, stock_quantity = $4
-    WHERE id = $5
-    RETURNING updated_at, avg_rating, rating_count
+    SET title = $1, author = $2, publisher = $3, publication_date = $4, language = $5, page_count = $6,
+        description = $7, updated_at = NOW()
+    WHERE id = $8
+    RETURNING created_at, updated_at, ` + bookPrice + `, ` + bookStock + `, avg_rating, rating_count
     `
-	args := []interface{}{book.Title, book.Author, book.Price, book.StockQuantity, book.ID}
+	args := []interface{}{
+		book.Title, book.Author, book.Publisher, book.PublicationDate, book.Language, book.PageCount,
+		book.Description, book.ID,
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&book.UpdatedAt, &book.AvgRating, &book.RatingCount)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&book.CreatedAt, &book.UpdatedAt, &book.Price, &book.StockQuantity, &book.AvgRating, &book.RatingCount)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if set.Authors {
+		if err = setBookAuthors(ctx, tx, book.ID, book.Authors); err != nil {
+			return err
+		}
+	}
+	if set.Categories {
+		if err = setBookCategories(ctx, tx, book.ID, book.Categories); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // Delete удаляет книгу из базы данных
@@ -156,18 +385,11 @@ func (m BookModel) Delete(id int64) error {
 // GetByTitle возвращает книгу по названию
 func (m *BookModel) GetByTitle(title string) (*Book, error) {
 	query := `
-    SELECT id, title, author, price, stock_quantity, created_at, updated_at, avg_rating, rating_count
+    SELECT ` + bookColumns + `
     FROM books
     WHERE title = $1
     `
-	var book Book
-	err := m.DB.QueryRow(query, title).Scan(
-		&book.ID, &book.Title, &book.Author, &book.Price, &book.StockQuantity, &book.CreatedAt, &book.UpdatedAt, &book.AvgRating, &book.RatingCount,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return &book, nil
+	return m.getOne(query, title)
 }
 
 // UpdateRating обновляет средний рейтинг и количество оценок книги
@@ -189,13 +411,20 @@ func (m BookModel) UpdateRating(bookID int64) error {
 	return err
 }
 
-// UpdateQuantity обновляет количество книг на складе
-func (m *BookModel) UpdateQuantity(bookID int64, newQuantity int) error {
-	query := `
-    UPDATE books
-    SET stock_quantity = $1
-    WHERE id = $2;
-    `
-	_, err := m.DB.Exec(query, newQuantity, bookID)
-	return err
+// ValidateBook проверяет поля книги и переданных с ней изданий
+func ValidateBook(v *validator.Validator, book *Book) {
+	v.Check(book.Title != "", "title", "must be provided")
+	v.Check(len(book.Title) <= 255, "title", "must not be more than 255 bytes long")
+	v.Check(book.Author != "", "author", "must be provided")
+	v.Check(len(book.Author) <= 255, "author", "must not be more than 255 bytes long")
+
+	v.Check(len(book.Publisher) <= 255, "publisher", "must not be more than 255 bytes long")
+	v.Check(book.PublicationDate.Before(time.Now()), "publication_date", "must not be in the future")
+	v.Check(book.Language == "" || validator.Matches(book.Language, LanguageRX), "language", "must be an ISO 639 language code, such as en or ru")
+	v.Check(book.PageCount >= 0, "page_count", "must not be negative")
+	v.Check(len(book.Description) <= 10_000, "description", "must not be more than 10000 bytes long")
+
+	validateBookAuthors(v, book.Authors)
+	validateBookCategories(v, book.Categories)
+	validateBookEditions(v, book.Editions)
 }
@@ -0,0 +1,356 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/filterexpr"
+	"github.com/lib/pq"
+)
+
+// Названия фасетов списка книг
+const (
+	FacetAuthor   = "author"
+	FacetPrice    = "price"
+	FacetRating   = "rating"
+	FacetCategory = "category"
+)
+
+// Facets перечисляет фасеты, которые можно запросить у списка книг
+var Facets = []string{FacetAuthor, FacetPrice, FacetRating, FacetCategory}
+
+// PriceBuckets задает границы ценовых диапазонов фасета price в центах базовой валюты.
+// Последний диапазон не ограничен сверху.
+var PriceBuckets = []int64{10_00, 20_00, 50_00, 100_00}
+
+// RatingBands задает пороги фасета rating: для каждого считаются книги со средним
+// рейтингом не ниже порога.
+var RatingBands = []int{4, 3, 2, 1}
+
+// BookFilterFields перечисляет поля, доступные в выражении filter= списка книг
+var BookFilterFields = map[string]filterexpr.Field{
+	"title":        {Column: "books.title", Type: filterexpr.String},
+	"author":       {Column: "books.author", Type: filterexpr.String},
+	"publisher":    {Column: "books.publisher", Type: filterexpr.String},
+	"language":     {Column: "books.language", Type: filterexpr.String},
+	"description":  {Column: "books.description", Type: filterexpr.String},
+	"price":        {Column: bookPrice, Type: filterexpr.Number},
+	"stock":        {Column: bookStock, Type: filterexpr.Number},
+	"rating":       {Column: "COALESCE(books.avg_rating, 0)", Type: filterexpr.Number},
+	"rating_count": {Column: "COALESCE(books.rating_count, 0)", Type: filterexpr.Number},
+	"pages":        {Column: "books.page_count", Type: filterexpr.Number},
+	"year":         {Column: "EXTRACT(YEAR FROM books.publication_date)", Type: filterexpr.Number},
+	"published":    {Column: "books.publication_date", Type: filterexpr.Date},
+}
+
+// BookQuery содержит параметры фильтрации списка книг. Пустые и нулевые значения
+// означают, что фильтр не применяется.
+type BookQuery struct {
+	Title         string
+	Author        string
+	PriceFrom     Money
+	PriceTo       Money
+	MinRating     float64
+	ISBN          string
+	Publisher     string
+	Language      string
+	PublishedFrom int
+	PublishedTo   int
+	AuthorIDs     []int64
+	CategoryIDs   []int64 // включая все подкатегории
+	Search        string
+
+	// Filter — разобранное выражение параметра filter=, см. BookFilterFields
+	Filter filterexpr.Expr
+}
+
+// FacetValue — одно значение фасета и количество книг с ним
+type FacetValue struct {
+	Value interface{} `json:"value"`
+	Label string      `json:"label"`
+	Count int         `json:"count"`
+}
+
+// sqlArgs накапливает аргументы запроса и выдает для каждого его плейсхолдер
+type sqlArgs []interface{}
+
+func (a *sqlArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// bookCondition — одно условие фильтрации книг. facet указывает, к какому фасету относится
+// условие: при подсчете этого фасета условие не применяется, чтобы в нем можно было выбрать
+// несколько значений.
+type bookCondition struct {
+	facet string
+	sql   func(a *sqlArgs) string
+}
+
+// conditions возвращает условия для заданных в запросе фильтров
+func (q BookQuery) conditions() []bookCondition {
+	var conds []bookCondition
+	add := func(facet string, sql func(a *sqlArgs) string) {
+		conds = append(conds, bookCondition{facet: facet, sql: sql})
+	}
+
+	if q.Title != "" {
+		add("", func(a *sqlArgs) string {
+			return "LOWER(books.title) LIKE LOWER(" + a.add("%"+q.Title+"%") + ")"
+		})
+	}
+	if q.Author != "" {
+		add(FacetAuthor, func(a *sqlArgs) string {
+			p := a.add("%" + q.Author + "%")
+			return `(LOWER(books.author) LIKE LOWER(` + p + `) OR EXISTS (
+                SELECT 1 FROM book_authors
+                INNER JOIN authors ON authors.id = book_authors.author_id
+                WHERE book_authors.book_id = books.id AND LOWER(authors.name) LIKE LOWER(` + p + `)))`
+		})
+	}
+	if len(q.AuthorIDs) > 0 {
+		add(FacetAuthor, func(a *sqlArgs) string {
+			return "books.id IN (SELECT book_id FROM book_authors WHERE author_id = ANY(" + a.add(pq.Array(q.AuthorIDs)) + "))"
+		})
+	}
+	if !q.PriceFrom.IsZero() {
+		add(FacetPrice, func(a *sqlArgs) string { return bookPrice + " >= " + a.add(q.PriceFrom) })
+	}
+	if !q.PriceTo.IsZero() {
+		add(FacetPrice, func(a *sqlArgs) string { return bookPrice + " <= " + a.add(q.PriceTo) })
+	}
+	if q.MinRating != 0 {
+		add(FacetRating, func(a *sqlArgs) string { return "books.avg_rating >= " + a.add(q.MinRating) })
+	}
+	if q.ISBN != "" {
+		add("", func(a *sqlArgs) string {
+			return "EXISTS (SELECT 1 FROM editions WHERE editions.book_id = books.id AND editions.isbn = " + a.add(q.ISBN) + ")"
+		})
+	}
+	if q.Publisher != "" {
+		add("", func(a *sqlArgs) string {
+			return "LOWER(books.publisher) LIKE LOWER(" + a.add("%"+q.Publisher+"%") + ")"
+		})
+	}
+	if q.Language != "" {
+		add("", func(a *sqlArgs) string { return "books.language = " + a.add(q.Language) })
+	}
+	if q.PublishedFrom != 0 {
+		add("", func(a *sqlArgs) string {
+			return "EXTRACT(YEAR FROM books.publication_date) >= " + a.add(q.PublishedFrom)
+		})
+	}
+	if q.PublishedTo != 0 {
+		add("", func(a *sqlArgs) string {
+			return "EXTRACT(YEAR FROM books.publication_date) <= " + a.add(q.PublishedTo)
+		})
+	}
+	if len(q.CategoryIDs) > 0 {
+		add(FacetCategory, func(a *sqlArgs) string {
+			where := "id = ANY(" + a.add(pq.Array(q.CategoryIDs)) + ")"
+			return "books.id IN (SELECT book_id FROM book_categories WHERE category_id IN (" + descendantsOf(where) + "))"
+		})
+	}
+	if q.Search != "" {
+		add("", func(a *sqlArgs) string { return "books.search_vector @@ " + q.tsquery(a) })
+	}
+	if q.Filter != nil {
+		add("", func(a *sqlArgs) string { return q.Filter.SQL(a.add) })
+	}
+
+	return conds
+}
+
+// tsquery возвращает выражение полнотекстового запроса для q.Search
+func (q BookQuery) tsquery(a *sqlArgs) string {
+	return "websearch_to_tsquery(" + a.add(SearchConfig(q.Search)) + "::regconfig, " + a.add(q.Search) + ")"
+}
+
+// where собирает условие WHERE из всех фильтров, кроме относящихся к фасету exclude.
+// Если фильтров нет, возвращается пустая строка.
+func (q BookQuery) where(a *sqlArgs, exclude string) string {
+	var parts []string
+	for _, cond := range q.conditions() {
+		if exclude != "" && cond.facet == exclude {
+			continue
+		}
+		parts = append(parts, cond.sql(a))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(parts, "\n        AND ")
+}
+
+// Facets считает значения запрошенных фасетов для книг, подходящих под фильтры q. Каждый
+// фасет считается без собственного фильтра.
+func (m BookModel) Facets(q BookQuery, names []string) (map[string][]*FacetValue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	facets := make(map[string][]*FacetValue, len(names))
+	for _, name := range names {
+		var (
+			values []*FacetValue
+			err    error
+		)
+		switch name {
+		case FacetAuthor:
+			values, err = m.authorFacet(ctx, q)
+		case FacetPrice:
+			values, err = m.priceFacet(ctx, q)
+		case FacetRating:
+			values, err = m.ratingFacet(ctx, q)
+		case FacetCategory:
+			values, err = m.categoryFacet(ctx, q)
+		default:
+			return nil, fmt.Errorf("unknown facet %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		facets[name] = values
+	}
+	return facets, nil
+}
+
+// authorFacet возвращает 20 авторов с наибольшим количеством подходящих книг
+func (m BookModel) authorFacet(ctx context.Context, q BookQuery) ([]*FacetValue, error) {
+	var args sqlArgs
+	query := `
+    SELECT authors.id, authors.name, COUNT(DISTINCT books.id)
+    FROM books
+    INNER JOIN book_authors ON book_authors.book_id = books.id
+    INNER JOIN authors ON authors.id = book_authors.author_id
+    ` + q.where(&args, FacetAuthor) + `
+    GROUP BY authors.id
+    ORDER BY COUNT(DISTINCT books.id) DESC, authors.name
+    LIMIT 20
+    `
+	return m.scanFacet(ctx, query, args)
+}
+
+// priceFacet возвращает количество книг в каждом ценовом диапазоне PriceBuckets
+func (m BookModel) priceFacet(ctx context.Context, q BookQuery) ([]*FacetValue, error) {
+	var args sqlArgs
+	bounds := make([]string, len(PriceBuckets))
+	for i, bound := range PriceBuckets {
+		bounds[i] = NewMoney(bound).String()
+	}
+	query := `
+    SELECT width_bucket(` + bookPrice + `, ` + args.add(pq.Array(bounds)) + `::numeric[]) AS bucket, '', COUNT(*)
+    FROM books
+    ` + q.where(&args, FacetPrice) + `
+    GROUP BY bucket
+    `
+	buckets, err := m.scanFacet(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int, len(buckets))
+	for _, bucket := range buckets {
+		counts[bucket.Value.(int64)] = bucket.Count
+	}
+
+	values := make([]*FacetValue, 0, len(PriceBuckets)+1)
+	for i := 0; i <= len(PriceBuckets); i++ {
+		var from int64
+		if i > 0 {
+			from = PriceBuckets[i-1]
+		}
+		label := priceLabel(from) + "+"
+		if i < len(PriceBuckets) {
+			label = priceLabel(from) + "-" + priceLabel(PriceBuckets[i])
+		}
+		values = append(values, &FacetValue{Value: label, Label: label, Count: counts[int64(i)]})
+	}
+	return values, nil
+}
+
+// priceLabel возвращает границу ценового диапазона для подписи: целые суммы без центов
+// ("10"), остальные с двумя знаками после точки ("9.99")
+func priceLabel(cents int64) string {
+	if cents%100 == 0 {
+		return strconv.FormatInt(cents/100, 10)
+	}
+	return NewMoney(cents).String()
+}
+
+// ratingFacet возвращает количество книг со средним рейтингом не ниже каждого порога RatingBands
+func (m BookModel) ratingFacet(ctx context.Context, q BookQuery) ([]*FacetValue, error) {
+	var args sqlArgs
+	columns := make([]string, len(RatingBands))
+	for i, band := range RatingBands {
+		columns[i] = fmt.Sprintf("COUNT(*) FILTER (WHERE books.avg_rating >= %d)", band)
+	}
+	query := `
+    SELECT ` + strings.Join(columns, ", ") + `
+    FROM books
+    ` + q.where(&args, FacetRating)
+
+	counts := make([]int, len(RatingBands))
+	dest := make([]interface{}, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]*FacetValue, len(RatingBands))
+	for i, band := range RatingBands {
+		values[i] = &FacetValue{Value: band, Label: fmt.Sprintf("%d+", band), Count: counts[i]}
+	}
+	return values, nil
+}
+
+// categoryFacet возвращает количество подходящих книг в каждой категории, включая книги
+// ее подкатегорий
+func (m BookModel) categoryFacet(ctx context.Context, q BookQuery) ([]*FacetValue, error) {
+	var args sqlArgs
+	query := `
+    WITH RECURSIVE closure AS (
+        SELECT id AS ancestor, id AS descendant FROM categories
+        UNION ALL
+        SELECT closure.ancestor, categories.id
+        FROM closure INNER JOIN categories ON categories.parent_id = closure.descendant
+    )
+    SELECT categories.id, categories.name, COUNT(DISTINCT books.id)
+    FROM books
+    INNER JOIN book_categories ON book_categories.book_id = books.id
+    INNER JOIN closure ON closure.descendant = book_categories.category_id
+    INNER JOIN categories ON categories.id = closure.ancestor
+    ` + q.where(&args, FacetCategory) + `
+    GROUP BY categories.id
+    ORDER BY LOWER(categories.name), categories.id
+    `
+	return m.scanFacet(ctx, query, args)
+}
+
+// scanFacet выполняет запрос, возвращающий строки (ID значения, подпись, количество)
+func (m BookModel) scanFacet(ctx context.Context, query string, args sqlArgs) ([]*FacetValue, error) {
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []*FacetValue{}
+	for rows.Next() {
+		var (
+			id    int64
+			value FacetValue
+		)
+		err := rows.Scan(&id, &value.Label, &value.Count)
+		if err != nil {
+			return nil, err
+		}
+		value.Value = id
+		values = append(values, &value)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,442 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrDuplicateCategory возвращается, если у родителя уже есть категория с таким именем
+	ErrDuplicateCategory = errors.New("duplicate category")
+
+	// ErrCategoryNotEmpty возвращается при попытке удалить категорию с подкатегориями
+	ErrCategoryNotEmpty = errors.New("category has subcategories")
+
+	// ErrCategoryCycle возвращается, если новый родитель категории является ее потомком
+	ErrCategoryCycle = errors.New("category cannot be moved under itself")
+)
+
+// Category представляет жанр или раздел каталога. Категории образуют дерево, у категорий
+// верхнего уровня ParentID равен 0.
+type Category struct {
+	ID        int64     `json:"id"`
+	ParentID  int64     `json:"parent_id,omitempty"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int32     `json:"version"`
+}
+
+// CategoryNode — категория вместе с подкатегориями, используется для вывода всего дерева
+type CategoryNode struct {
+	*Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// CategoryCount — категория и количество книг в ней и во всех ее потомках
+type CategoryCount struct {
+	*Category
+	BookCount int `json:"book_count"`
+}
+
+// BookCategory — краткое описание категории в составе книги
+type BookCategory struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// CategoryModel обрабатывает операции с категориями в базе данных
+type CategoryModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// descendantsOf возвращает подзапрос, выбирающий ID категорий, подходящих под условие
+// where (например, "id = $1"), и всех их потомков
+func descendantsOf(where string) string {
+	return `
+    WITH RECURSIVE descendants AS (
+        SELECT id FROM categories WHERE ` + where + `
+        UNION ALL
+        SELECT categories.id FROM categories INNER JOIN descendants ON categories.parent_id = descendants.id
+    )
+    SELECT id FROM descendants`
+}
+
+// Insert добавляет новую категорию
+func (m CategoryModel) Insert(category *Category) error {
+	query := `
+        INSERT INTO categories (parent_id, name)
+        VALUES (NULLIF($1, 0), $2)
+        RETURNING id, created_at, updated_at, version
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, category.ParentID, category.Name).Scan(
+		&category.ID, &category.CreatedAt, &category.UpdatedAt, &category.Version,
+	)
+	if err != nil {
+		if isDuplicateCategory(err) {
+			return ErrDuplicateCategory
+		}
+		return err
+	}
+	return nil
+}
+
+// Get возвращает категорию по ID
+func (m CategoryModel) Get(id int64) (*Category, error) {
+	query := `
+    SELECT id, COALESCE(parent_id, 0), name, created_at, updated_at, version
+    FROM categories
+    WHERE id = $1
+    `
+	var category Category
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&category.ID, &category.ParentID, &category.Name, &category.CreatedAt, &category.UpdatedAt, &category.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &category, nil
+}
+
+// Tree возвращает все дерево категорий. Категории одного уровня упорядочены по имени.
+func (m CategoryModel) Tree() ([]*CategoryNode, error) {
+	query := `
+    SELECT id, COALESCE(parent_id, 0), name, created_at, updated_at, version
+    FROM categories
+    ORDER BY LOWER(name), id
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*Category
+	for rows.Next() {
+		var category Category
+		err := rows.Scan(
+			&category.ID, &category.ParentID, &category.Name, &category.CreatedAt, &category.UpdatedAt, &category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int64]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{Category: category, Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if parent, ok := nodes[category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+// Path возвращает цепочку категорий от корня дерева до категории id включительно
+func (m CategoryModel) Path(id int64) ([]*Category, error) {
+	query := `
+    WITH RECURSIVE ancestors AS (
+        SELECT id, parent_id, name, created_at, updated_at, version, 0 AS depth
+        FROM categories WHERE id = $1
+        UNION ALL
+        SELECT categories.id, categories.parent_id, categories.name, categories.created_at,
+               categories.updated_at, categories.version, ancestors.depth + 1
+        FROM categories INNER JOIN ancestors ON categories.id = ancestors.parent_id
+    )
+    SELECT id, COALESCE(parent_id, 0), name, created_at, updated_at, version
+    FROM ancestors
+    ORDER BY depth DESC
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	path := []*Category{}
+	for rows.Next() {
+		var category Category
+		err := rows.Scan(
+			&category.ID, &category.ParentID, &category.Name, &category.CreatedAt, &category.UpdatedAt, &category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, &category)
+	}
+	return path, rows.Err()
+}
+
+// BookCount возвращает количество книг в категории id и всех ее потомках
+func (m CategoryModel) BookCount(id int64) (int, error) {
+	query := `
+    SELECT COUNT(DISTINCT book_id)
+    FROM book_categories
+    WHERE category_id IN (` + descendantsOf("id = $1") + `)
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&count)
+	return count, err
+}
+
+// Subcategories возвращает прямых потомков категории id. Для каждой подкатегории считаются
+// книги в ней самой и во всех ее потомках, книга в нескольких из них учитывается один раз.
+func (m CategoryModel) Subcategories(id int64) ([]*CategoryCount, error) {
+	query := `
+    WITH RECURSIVE subtree AS (
+        SELECT id, id AS root FROM categories WHERE parent_id = $1
+        UNION ALL
+        SELECT categories.id, subtree.root FROM categories INNER JOIN subtree ON categories.parent_id = subtree.id
+    )
+    SELECT categories.id, COALESCE(categories.parent_id, 0), categories.name, categories.created_at,
+           categories.updated_at, categories.version, COUNT(DISTINCT book_categories.book_id)
+    FROM categories
+    LEFT JOIN subtree ON subtree.root = categories.id
+    LEFT JOIN book_categories ON book_categories.category_id = subtree.id
+    WHERE categories.parent_id = $1
+    GROUP BY categories.id
+    ORDER BY LOWER(categories.name), categories.id
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subcategories := []*CategoryCount{}
+	for rows.Next() {
+		count := CategoryCount{Category: &Category{}}
+		err := rows.Scan(
+			&count.ID, &count.ParentID, &count.Name, &count.CreatedAt, &count.UpdatedAt, &count.Version,
+			&count.BookCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		subcategories = append(subcategories, &count)
+	}
+	return subcategories, rows.Err()
+}
+
+// GetByIDs возвращает категории с указанными ID. Отсутствующие ID в результат не попадают.
+func (m CategoryModel) GetByIDs(ids []int64) (map[int64]*Category, error) {
+	query := `
+    SELECT id, COALESCE(parent_id, 0), name, created_at, updated_at, version
+    FROM categories
+    WHERE id = ANY($1)
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[int64]*Category)
+	for rows.Next() {
+		var category Category
+		err := rows.Scan(
+			&category.ID, &category.ParentID, &category.Name, &category.CreatedAt, &category.UpdatedAt, &category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories[category.ID] = &category
+	}
+	return categories, rows.Err()
+}
+
+// Update сохраняет изменения категории. Перенос категории под ее же потомка возвращает
+// ErrCategoryCycle, одновременное изменение — ErrEditConflict.
+func (m CategoryModel) Update(category *Category) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if category.ParentID != 0 {
+		var cycle bool
+		err := m.DB.QueryRowContext(ctx, `SELECT $2 IN (`+descendantsOf("id = $1")+`)`, category.ID, category.ParentID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCategoryCycle
+		}
+	}
+
+	query := `
+    UPDATE categories
+    SET parent_id = NULLIF($1, 0), name = $2, updated_at = NOW(), version = version + 1
+    WHERE id = $3 AND version = $4
+    RETURNING updated_at, version
+    `
+	args := []interface{}{category.ParentID, category.Name, category.ID, category.Version}
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&category.UpdatedAt, &category.Version)
+	if err != nil {
+		switch {
+		case isDuplicateCategory(err):
+			return ErrDuplicateCategory
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete удаляет категорию. Книги остаются в каталоге, удаляется только их привязка.
+// Категорию с подкатегориями удалить нельзя, сначала их нужно перенести или удалить.
+func (m CategoryModel) Delete(id int64) error {
+	query := `
+    DELETE FROM categories
+    WHERE id = $1
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		if strings.Contains(err.Error(), `violates foreign key constraint "categories_parent_id_fkey"`) {
+			return ErrCategoryNotEmpty
+		}
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// loadBookCategories заполняет поле Categories у переданных книг одним запросом
+func loadBookCategories(ctx context.Context, db *sql.DB, books ...*Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(books))
+	byID := make(map[int64]*Book, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+		byID[book.ID] = book
+	}
+
+	query := `
+    SELECT book_categories.book_id, categories.id, categories.name
+    FROM book_categories
+    INNER JOIN categories ON categories.id = book_categories.category_id
+    WHERE book_categories.book_id = ANY($1)
+    ORDER BY book_categories.book_id, LOWER(categories.name)
+    `
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			bookID   int64
+			category BookCategory
+		)
+		err := rows.Scan(&bookID, &category.ID, &category.Name)
+		if err != nil {
+			return err
+		}
+		if book := byID[bookID]; book != nil {
+			book.Categories = append(book.Categories, &category)
+		}
+	}
+	return rows.Err()
+}
+
+// setBookCategories заменяет список категорий книги
+func setBookCategories(ctx context.Context, tx *sql.Tx, bookID int64, categories []*BookCategory) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM book_categories WHERE book_id = $1`, bookID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+    INSERT INTO book_categories (book_id, category_id)
+    VALUES ($1, $2)
+    `
+	for _, category := range categories {
+		_, err = tx.ExecContext(ctx, query, bookID, category.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateCategory проверяет поля категории
+func ValidateCategory(v *validator.Validator, category *Category) {
+	v.Check(strings.TrimSpace(category.Name) != "", "name", "must be provided")
+	v.Check(len(category.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(category.ParentID >= 0, "parent_id", "must be a valid category id")
+	v.Check(category.ID == 0 || category.ParentID != category.ID, "parent_id", "must not be the category itself")
+}
+
+// validateBookCategories проверяет список категорий книги
+func validateBookCategories(v *validator.Validator, categories []*BookCategory) {
+	seen := make(map[int64]bool)
+	for _, category := range categories {
+		v.Check(category.ID > 0, "categories", "must contain valid category ids")
+		v.Check(!seen[category.ID], "categories", "must not contain duplicate values")
+		seen[category.ID] = true
+	}
+}
+
+// isDuplicateCategory сообщает, нарушает ли ошибка уникальность имени среди соседних категорий
+func isDuplicateCategory(err error) bool {
+	return strings.Contains(err.Error(), `violates unique constraint "categories_parent_name_key"`)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 )
@@ -45,7 +46,7 @@ func (m *CommentModel) Insert(comment *Comment) error {
 // Get получает комментарий по его ID.
 func (m *CommentModel) Get(id int64) (*Comment, error) {
 	query := `
-		SELECT id, user_id, book_id, content, created_at
+		SELECT id, COALESCE(user_id, 0), book_id, content, created_at
 		FROM comments
 		WHERE id = $1
 	`
@@ -61,17 +62,80 @@ func (m *CommentModel) Get(id int64) (*Comment, error) {
 	return &comment, nil
 }
 
-// GetAllByBook получает все комментарии к заданной книге.
-func (m *CommentModel) GetAllByBook(bookID int64) ([]*Comment, error) {
+// GetAllByBook получает комментарии к заданной книге постранично, по номеру страницы или
+// по курсору.
+func (m *CommentModel) GetAllByBook(bookID int64, filters Filters) ([]*Comment, Metadata, error) {
+	columns := map[string]string{
+		"id":         "comments.id",
+		"created_at": "comments.created_at",
+	}
+
+	var args sqlArgs
+	conditions := "WHERE comments.book_id = " + args.add(bookID)
+	if keyset := filters.keyset(columns, &args); keyset != "" {
+		conditions += " AND " + keyset
+	}
+
+	total := "count(*) OVER()"
+	if filters.cursorMode() {
+		total = "0"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, id, COALESCE(user_id, 0), book_id, content, created_at
+		FROM comments
+		%s
+		%s
+		%s
+	`, total, conditions, filters.orderBy(columns), filters.pageLimit(&args))
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	comments := []*Comment{}
+	for rows.Next() {
+		var comment Comment
+		err := rows.Scan(&totalRecords, &comment.ID, &comment.UserID, &comment.BookID, &comment.Content, &comment.CreatedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		comments = append(comments, &comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	comments, metadata := keysetPage(filters, comments, totalRecords)
+	return comments, metadata, nil
+}
+
+// sortValue возвращает значение комментария для ключа сортировки, используется в курсорах
+func (c *Comment) sortValue(column string) interface{} {
+	if column == "created_at" {
+		return c.CreatedAt
+	}
+	return c.ID
+}
+
+// GetAllForUser получает все комментарии, оставленные пользователем.
+func (m *CommentModel) GetAllForUser(userID int64) ([]*Comment, error) {
 	query := `
 		SELECT id, user_id, book_id, content, created_at
 		FROM comments
-		WHERE book_id = $1
+		WHERE user_id = $1
+		ORDER BY created_at
 	`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, bookID)
+	rows, err := m.DB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
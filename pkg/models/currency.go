@@ -0,0 +1,300 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+)
+
+var (
+	// ErrUnknownCurrency возвращается, если для валюты не задан курс
+	ErrUnknownCurrency = errors.New("unknown currency")
+
+	// ErrBaseCurrency возвращается при попытке удалить курс базовой валюты
+	ErrBaseCurrency = errors.New("the base currency cannot be deleted")
+
+	// ErrAmountOutOfRange возвращается, если пересчитанная сумма не помещается в столбец
+	// charged_total NUMERIC(14, 2)
+	ErrAmountOutOfRange = errors.New("converted amount is out of range")
+
+	// CurrencyRX проверяет код валюты ISO 4217
+	CurrencyRX = regexp.MustCompile("^[A-Z]{3}$")
+
+	// RateRX проверяет курс: до 10 цифр до точки и до 8 после, как в NUMERIC(18, 8)
+	RateRX = regexp.MustCompile(`^\d{1,10}(\.\d{1,8})?$`)
+
+	// RoundingModes перечисляет правила округления пересчитанных сумм
+	RoundingModes = []string{"nearest", "up", "down"}
+)
+
+// CurrencyRate — курс валюты к BaseCurrency: сколько единиц Currency стоит одна единица
+// базовой валюты. Пересчитанные суммы округляются до кратного RoundingStep по правилу
+// RoundingMode: "nearest" — до ближайшего, "up" — вверх, "down" — вниз. Например, цены в
+// тенге можно округлять до целых с шагом 1.00, а в евро оставить шаг 0.01.
+type CurrencyRate struct {
+	Currency     string    `json:"currency"`
+	Rate         string    `json:"rate"`
+	RoundingStep Money     `json:"rounding_step"`
+	RoundingMode string    `json:"rounding_mode"`
+	UpdatedBy    int64     `json:"updated_by,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Version      int32     `json:"version"`
+}
+
+// CurrencyModel обрабатывает операции с курсами валют в базе данных
+type CurrencyModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// currencyRateColumns перечисляет столбцы курса в порядке, в котором их считывает scanDest
+const currencyRateColumns = `currency, rate, rounding_step, rounding_mode, COALESCE(updated_by, 0), updated_at, version`
+
+// scanDest возвращает указатели на поля курса в порядке столбцов currencyRateColumns
+func (r *CurrencyRate) scanDest() []interface{} {
+	return []interface{}{&r.Currency, &r.Rate, &r.RoundingStep, &r.RoundingMode, &r.UpdatedBy, &r.UpdatedAt, &r.Version}
+}
+
+// afterScan приводит считанный курс к виду, в котором он отдается клиентам: шаг округления
+// указывается в самой валюте, а у курса убираются лишние нули
+func (r *CurrencyRate) afterScan() {
+	r.RoundingStep.Currency = r.Currency
+	if strings.Contains(r.Rate, ".") {
+		r.Rate = strings.TrimSuffix(strings.TrimRight(r.Rate, "0"), ".")
+	}
+}
+
+// rowQuerier — общее для *sql.DB и *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// GetAll возвращает курсы всех валют, упорядоченные по коду валюты
+func (m CurrencyModel) GetAll() ([]*CurrencyRate, error) {
+	query := `
+    SELECT ` + currencyRateColumns + `
+    FROM currency_rates
+    ORDER BY currency
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*CurrencyRate{}
+	for rows.Next() {
+		var rate CurrencyRate
+		if err := rows.Scan(rate.scanDest()...); err != nil {
+			return nil, err
+		}
+		rate.afterScan()
+		rates = append(rates, &rate)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// Get возвращает курс валюты currency. Если курс не задан, возвращается ErrUnknownCurrency.
+func (m CurrencyModel) Get(currency string) (*CurrencyRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getCurrencyRate(ctx, m.DB, currency, "")
+}
+
+// Upsert добавляет курс валюты или обновляет существующий от имени пользователя updatedBy
+// (0, если курс загружен из файла)
+func (m CurrencyModel) Upsert(rate *CurrencyRate, updatedBy int64) error {
+	query := `
+    INSERT INTO currency_rates (currency, rate, rounding_step, rounding_mode, updated_by)
+    VALUES ($1, $2, $3, $4, NULLIF($5, 0))
+    ON CONFLICT (currency) DO UPDATE
+    SET rate = EXCLUDED.rate, rounding_step = EXCLUDED.rounding_step, rounding_mode = EXCLUDED.rounding_mode,
+        updated_by = EXCLUDED.updated_by, updated_at = NOW(), version = currency_rates.version + 1
+    RETURNING updated_at, version
+    `
+	args := []interface{}{rate.Currency, rate.Rate, rate.RoundingStep, rate.RoundingMode, updatedBy}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rate.UpdatedBy = updatedBy
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&rate.UpdatedAt, &rate.Version)
+}
+
+// Delete удаляет курс валюты. Пользователи, выбравшие эту валюту, снова видят цены в
+// базовой валюте.
+func (m CurrencyModel) Delete(currency string) error {
+	if currency == BaseCurrency {
+		return ErrBaseCurrency
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM currency_rates WHERE currency = $1`, currency)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// getCurrencyRate считывает курс валюты через db или транзакцию. lock добавляется к запросу,
+// например "FOR SHARE", чтобы курс не изменился до конца транзакции.
+func getCurrencyRate(ctx context.Context, q rowQuerier, currency, lock string) (*CurrencyRate, error) {
+	query := `
+    SELECT ` + currencyRateColumns + `
+    FROM currency_rates
+    WHERE currency = $1
+    ` + lock
+	var rate CurrencyRate
+	err := q.QueryRowContext(ctx, query, currency).Scan(rate.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrUnknownCurrency
+		default:
+			return nil, err
+		}
+	}
+	rate.afterScan()
+	return &rate, nil
+}
+
+// maxConvertedAmount — наибольшая по модулю сумма в центах, которая помещается в столбец
+// charged_total NUMERIC(14, 2)
+const maxConvertedAmount = 99_999_999_999_999
+
+// Convert пересчитывает сумму в базовой валюте в валюту курса и округляет ее по правилу
+// курса. Nil-курс означает базовую валюту, и сумма возвращается без изменений. Если
+// пересчитанная сумма больше maxConvertedAmount, возвращается ErrAmountOutOfRange.
+func (r *CurrencyRate) Convert(m Money) (Money, error) {
+	if r == nil || m.Currency == r.Currency {
+		return m, nil
+	}
+	m.mustMatch(NewMoney(0))
+
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok {
+		panic("models: invalid exchange rate " + r.Rate)
+	}
+	step := r.RoundingStep.Amount
+	if step <= 0 {
+		step = 1
+	}
+
+	// Сумма в центах новой валюты, выраженная в шагах округления
+	steps := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	steps.Quo(steps, new(big.Rat).SetInt64(step))
+	amount := new(big.Int).Mul(roundRat(steps, r.RoundingMode), big.NewInt(step))
+	if amount.CmpAbs(big.NewInt(maxConvertedAmount)) > 0 {
+		return Money{}, ErrAmountOutOfRange
+	}
+	return Money{Amount: amount.Int64(), Currency: r.Currency}, nil
+}
+
+// roundRat округляет x до целого по правилу mode. "nearest" округляет половину вверх.
+func roundRat(x *big.Rat, mode string) *big.Int {
+	den := x.Denom()
+	quo, mod := new(big.Int).DivMod(x.Num(), den, new(big.Int))
+	switch mode {
+	case "up":
+		if mod.Sign() != 0 {
+			quo.Add(quo, big.NewInt(1))
+		}
+	case "nearest":
+		if new(big.Int).Lsh(mod, 1).Cmp(den) >= 0 {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return quo
+}
+
+// ConvertBooks пересчитывает цены книг и их изданий в валюту курса
+func (r *CurrencyRate) ConvertBooks(books ...*Book) error {
+	if r == nil {
+		return nil
+	}
+	for _, book := range books {
+		price, err := r.Convert(book.Price)
+		if err != nil {
+			return err
+		}
+		book.Price = price
+		if err := r.ConvertEditions(book.Editions...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertEditions пересчитывает цены изданий в валюту курса
+func (r *CurrencyRate) ConvertEditions(editions ...*Edition) error {
+	if r == nil {
+		return nil
+	}
+	for _, edition := range editions {
+		price, err := r.Convert(edition.Price)
+		if err != nil {
+			return err
+		}
+		edition.Price = price
+	}
+	return nil
+}
+
+// ConvertVolumes пересчитывает цены книг серии в валюту курса
+func (r *CurrencyRate) ConvertVolumes(volumes ...*SeriesVolume) error {
+	if r == nil {
+		return nil
+	}
+	for _, volume := range volumes {
+		price, err := r.Convert(volume.Price)
+		if err != nil {
+			return err
+		}
+		volume.Price = price
+	}
+	return nil
+}
+
+// ValidateCurrency проверяет код валюты, сохраняемый под ключом key
+func ValidateCurrency(v *validator.Validator, currency, key string) {
+	v.Check(validator.Matches(currency, CurrencyRX), key, "must be a three-letter ISO 4217 currency code")
+}
+
+// ValidateCurrencyRate проверяет курс валюты. Курс базовой валюты всегда равен 1.
+func ValidateCurrencyRate(v *validator.Validator, rate *CurrencyRate) {
+	ValidateCurrency(v, rate.Currency, "currency")
+
+	if !validator.Matches(rate.Rate, RateRX) {
+		v.AddError("rate", "must be a decimal number with at most 10 digits before and 8 after the point")
+	} else {
+		value, _ := new(big.Rat).SetString(rate.Rate)
+		v.Check(value.Sign() > 0, "rate", "must be greater than zero")
+		v.Check(rate.Currency != BaseCurrency || value.Cmp(big.NewRat(1, 1)) == 0, "rate", "must be 1 for the base currency")
+	}
+
+	v.Check(rate.RoundingStep.Amount > 0, "rounding_step", "must be greater than zero")
+	v.Check(validator.In(rate.RoundingMode, RoundingModes...), "rounding_mode", "must be one of "+strings.Join(RoundingModes, ", "))
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestRoundRat(t *testing.T) {
+	tests := []struct {
+		num, den int64
+		mode     string
+		want     int64
+	}{
+		{7, 3, "nearest", 2},
+		{5, 3, "nearest", 2},
+		{5, 2, "nearest", 3},
+		{-5, 2, "nearest", -2},
+		{-7, 3, "nearest", -2},
+		{-5, 3, "nearest", -2},
+		{7, 3, "up", 3},
+		{-7, 3, "up", -2},
+		{7, 3, "down", 2},
+		{-7, 3, "down", -3},
+		{4, 1, "nearest", 4},
+		{4, 1, "up", 4},
+		{-4, 1, "down", -4},
+	}
+
+	for _, tt := range tests {
+		got := roundRat(big.NewRat(tt.num, tt.den), tt.mode)
+		if got.Int64() != tt.want {
+			t.Errorf("roundRat(%d/%d, %q) = %s, want %d", tt.num, tt.den, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestCurrencyRateConvert(t *testing.T) {
+	eur := &CurrencyRate{Currency: "EUR", Rate: "0.9", RoundingStep: NewMoney(1), RoundingMode: "nearest"}
+	kzt := func(mode string) *CurrencyRate {
+		return &CurrencyRate{Currency: "KZT", Rate: "450.5", RoundingStep: NewMoney(100), RoundingMode: mode}
+	}
+
+	tests := []struct {
+		name   string
+		rate   *CurrencyRate
+		amount int64
+		want   Money
+		error  error
+	}{
+		{"nil rate is the base currency", nil, 1299, NewMoney(1299), nil},
+		{"base currency", &CurrencyRate{Currency: BaseCurrency, Rate: "1", RoundingStep: NewMoney(1), RoundingMode: "nearest"}, 1299, NewMoney(1299), nil},
+		{"cents", eur, 1299, Money{Amount: 1169, Currency: "EUR"}, nil},
+		{"negative amount", eur, -1299, Money{Amount: -1169, Currency: "EUR"}, nil},
+		{"zero", eur, 0, Money{Amount: 0, Currency: "EUR"}, nil},
+		{"whole units nearest", kzt("nearest"), 1299, Money{Amount: 585200, Currency: "KZT"}, nil},
+		{"whole units up", kzt("up"), 1299, Money{Amount: 585200, Currency: "KZT"}, nil},
+		{"whole units down", kzt("down"), 1299, Money{Amount: 585100, Currency: "KZT"}, nil},
+		{"exact multiple of the step", kzt("up"), 200, Money{Amount: 90100, Currency: "KZT"}, nil},
+		{"zero step means cents", &CurrencyRate{Currency: "EUR", Rate: "0.5", RoundingMode: "up"}, 3, Money{Amount: 2, Currency: "EUR"}, nil},
+		{"largest amount", &CurrencyRate{Currency: "XXX", Rate: "1", RoundingStep: NewMoney(1), RoundingMode: "nearest"}, maxConvertedAmount, Money{Amount: maxConvertedAmount, Currency: "XXX"}, nil},
+		{"out of range", &CurrencyRate{Currency: "XXX", Rate: "9999999999.99999999", RoundingStep: NewMoney(1), RoundingMode: "nearest"}, 100_000_000, Money{}, ErrAmountOutOfRange},
+		{"negative out of range", &CurrencyRate{Currency: "XXX", Rate: "1000", RoundingStep: NewMoney(1), RoundingMode: "nearest"}, -maxConvertedAmount, Money{}, ErrAmountOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rate.Convert(NewMoney(tt.amount))
+			if !errors.Is(err, tt.error) {
+				t.Fatalf("Convert(%d) error = %v, want %v", tt.amount, err, tt.error)
+			}
+			if got != tt.want {
+				t.Errorf("Convert(%d) = %+v, want %+v", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
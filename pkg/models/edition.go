@@ -0,0 +1,287 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+	"github.com/lib/pq"
+)
+
+// EditionFormats перечисляет допустимые форматы издания
+var EditionFormats = []string{"hardcover", "paperback", "ebook", "audiobook"}
+
+// Edition представляет издание книги в определенном формате. У каждого издания свои ISBN,
+// цена и остаток на складе, а отзывы и оценки относятся к книге целиком.
+type Edition struct {
+	ID            int64     `json:"id"`
+	BookID        int64     `json:"book_id"`
+	Format        string    `json:"format"`
+	ISBN          string    `json:"isbn"`
+	Price         Money     `json:"price"`
+	StockQuantity int       `json:"stock_quantity"`
+	PageCount     int       `json:"page_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Version       int32     `json:"version"`
+}
+
+// EditionModel обрабатывает операции с изданиями в базе данных
+type EditionModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// editionColumns перечисляет столбцы издания в порядке, в котором их считывает scanDest
+const editionColumns = `editions.id, editions.book_id, editions.format, COALESCE(editions.isbn, ''), editions.price,
+        editions.stock_quantity, editions.page_count, editions.created_at, editions.updated_at, editions.version`
+
+// scanDest возвращает указатели на поля издания в порядке столбцов editionColumns
+func (e *Edition) scanDest() []interface{} {
+	return []interface{}{
+		&e.ID, &e.BookID, &e.Format, &e.ISBN, &e.Price,
+		&e.StockQuantity, &e.PageCount, &e.CreatedAt, &e.UpdatedAt, &e.Version,
+	}
+}
+
+// Insert добавляет новое издание книги edition.BookID. Начальная цена записывается в
+// историю цен от имени пользователя createdBy (0, если неизвестен).
+func (m EditionModel) Insert(edition *Edition, createdBy int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = insertEdition(ctx, tx, edition, createdBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get возвращает издание по ID
+func (m EditionModel) Get(id int64) (*Edition, error) {
+	query := `
+    SELECT ` + editionColumns + `
+    FROM editions
+    WHERE id = $1
+    `
+	return m.getOne(query, id)
+}
+
+// GetByISBN возвращает издание по ISBN. ISBN должен быть приведен к виду NormalizeISBN.
+func (m EditionModel) GetByISBN(isbn string) (*Edition, error) {
+	query := `
+    SELECT ` + editionColumns + `
+    FROM editions
+    WHERE isbn = $1
+    `
+	return m.getOne(query, isbn)
+}
+
+// getOne выполняет запрос, возвращающий одно издание
+func (m EditionModel) getOne(query string, args ...interface{}) (*Edition, error) {
+	var edition Edition
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(edition.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &edition, nil
+}
+
+// Update сохраняет изменения издания. Изменение цены записывается в историю цен от имени
+// пользователя changedBy. Если запись была изменена или удалена с момента чтения,
+// возвращается ErrEditConflict.
+func (m EditionModel) Update(edition *Edition, changedBy int64) error {
+	query := `
+    UPDATE editions
+    SET format = $1, isbn = NULLIF($2, ''), price = $3, stock_quantity = $4, page_count = $5,
+        updated_at = NOW(), version = version + 1
+    WHERE id = $6 AND version = $7
+    RETURNING updated_at, version
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Прежняя цена считывается с блокировкой строки, чтобы запись в истории соответствовала
+	// именно этому изменению
+	var oldPrice Money
+	err = tx.QueryRowContext(ctx, `SELECT price FROM editions WHERE id = $1 AND version = $2 FOR UPDATE`,
+		edition.ID, edition.Version).Scan(&oldPrice)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	args := []interface{}{
+		edition.Format, edition.ISBN, edition.Price, edition.StockQuantity, edition.PageCount,
+		edition.ID, edition.Version,
+	}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&edition.UpdatedAt, &edition.Version)
+	if err != nil {
+		switch {
+		case isDuplicateISBN(err):
+			return ErrDuplicateISBN
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if !oldPrice.Equal(edition.Price) {
+		err = recordPriceChange(ctx, tx, edition.ID, &oldPrice, edition.Price, changedBy, 0)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete удаляет издание. Покупки этого издания сохраняются без ссылки на него.
+func (m EditionModel) Delete(id int64) error {
+	query := `
+    DELETE FROM editions
+    WHERE id = $1
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// insertEdition добавляет издание в рамках транзакции и начинает его историю цен
+func insertEdition(ctx context.Context, tx *sql.Tx, edition *Edition, createdBy int64) error {
+	query := `
+    INSERT INTO editions (book_id, format, isbn, price, stock_quantity, page_count)
+    VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+    RETURNING id, created_at, updated_at, version
+    `
+	args := []interface{}{
+		edition.BookID, edition.Format, edition.ISBN, edition.Price, edition.StockQuantity, edition.PageCount,
+	}
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&edition.ID, &edition.CreatedAt, &edition.UpdatedAt, &edition.Version)
+	if err != nil {
+		if isDuplicateISBN(err) {
+			return ErrDuplicateISBN
+		}
+		return err
+	}
+	return recordPriceChange(ctx, tx, edition.ID, nil, edition.Price, createdBy, 0)
+}
+
+// loadBookEditions заполняет поле Editions у переданных книг одним запросом
+func loadBookEditions(ctx context.Context, db *sql.DB, books ...*Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(books))
+	byID := make(map[int64]*Book, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+		byID[book.ID] = book
+	}
+
+	query := `
+    SELECT ` + editionColumns + `
+    FROM editions
+    WHERE book_id = ANY($1)
+    ORDER BY book_id, price, id
+    `
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var edition Edition
+		if err := rows.Scan(edition.scanDest()...); err != nil {
+			return err
+		}
+		if book := byID[edition.BookID]; book != nil {
+			book.Editions = append(book.Editions, &edition)
+		}
+	}
+	return rows.Err()
+}
+
+// ValidateEdition проверяет поля издания. ISBN, если он указан, приводится к виду NormalizeISBN.
+func ValidateEdition(v *validator.Validator, edition *Edition) {
+	validateEdition(v, edition, "")
+}
+
+// validateEdition проверяет поля издания, добавляя prefix к ключам ошибок
+func validateEdition(v *validator.Validator, edition *Edition, prefix string) {
+	v.Check(validator.In(edition.Format, EditionFormats...), prefix+"format",
+		"must be one of "+strings.Join(EditionFormats, ", "))
+	validatePrice(v, &edition.Price, prefix+"price")
+	v.Check(edition.StockQuantity >= 0, prefix+"stock_quantity", "must not be negative")
+	v.Check(edition.PageCount >= 0, prefix+"page_count", "must not be negative")
+
+	if edition.ISBN != "" {
+		isbn, err := NormalizeISBN(edition.ISBN)
+		if err != nil {
+			v.AddError(prefix+"isbn", "must be a valid ISBN-10 or ISBN-13")
+		} else {
+			edition.ISBN = isbn
+		}
+	}
+}
+
+// validateBookEditions проверяет издания, переданные вместе с книгой. Ключи ошибок имеют
+// вид editions[0].price.
+func validateBookEditions(v *validator.Validator, editions []*Edition) {
+	seen := make(map[string]bool)
+	for i, edition := range editions {
+		validateEdition(v, edition, fmt.Sprintf("editions[%d].", i))
+		if edition.ISBN != "" {
+			v.Check(!seen[edition.ISBN], "editions", "must not contain the same ISBN twice")
+			seen[edition.ISBN] = true
+		}
+	}
+}
+
+// isDuplicateISBN сообщает, нарушает ли ошибка ограничение уникальности ISBN
+func isDuplicateISBN(err error) bool {
+	return strings.Contains(err.Error(), `violates unique constraint "editions_isbn_key"`)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// EmailChangeModel struct wraps a sql.DB connection pool and allows us to work with the
+// email_changes table. Each row holds the address a user asked to switch to, keyed by the
+// hash of the email change token that was sent to that address.
+type EmailChangeModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// Insert stores the requested email address for an email change token. The token itself must
+// already be in the tokens table.
+func (m EmailChangeModel) Insert(token *Token, email string) error {
+	query := `
+		INSERT INTO email_changes (token_hash, email)
+		VALUES ($1, $2)
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, token.Hash, email)
+	return err
+}
+
+// GetForToken returns the requested email address for a plaintext email change token.
+func (m EmailChangeModel) GetForToken(tokenPlaintext string) (string, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		SELECT email_changes.email
+		FROM email_changes
+		INNER JOIN tokens
+			ON tokens.hash = email_changes.token_hash
+		WHERE email_changes.token_hash = $1
+			AND tokens.scope = $2
+			AND tokens.expiry > $3
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var email string
+	err := m.DB.QueryRowContext(ctx, query, tokenHash[:], ScopeEmailChange, time.Now()).Scan(&email)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return email, nil
+}
@@ -1,26 +1,45 @@
 package models
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"math"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Zhan1bek/BookStore/pkg/validator"
 )
 
+// Filters holds the pagination and sorting parameters of a list request. Lists are paged
+// either by page number or, when After or Before holds a cursor from a previous response, by
+// keyset: the rows strictly after or before the cursor in the sort order. Scope identifies the
+// list and its filters (everything but the paging and sorting parameters); a cursor is only
+// accepted by the list it was created for.
 type Filters struct {
 	Page         int
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	After        string
+	Before       string
+	Scope        string
 }
 
-// Define a new Metadata struct for holding the pagination metadata.
+// Define a new Metadata struct for holding the pagination metadata. In cursor mode only the
+// page size and the cursors are set.
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -39,32 +58,41 @@ func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 
 func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.Page > 0, "page", "must be greater than 0")
-	v.Check(f.Page <= 10_000_0000, "", "must be a maximum of 10 million")
+	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
 	v.Check(f.PageSize > 0, "page_size", "must be greater than 0")
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
-	// Check that the sort parameter matches a value in the safelist.
-	v.Check(validator.In(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
-}
-
-// Check that the client-provided Sort field matches one of the entries in our safelist
-// and if it does, extract the column name from the Sort field by stripping the leading
-// hyphen character (if one exists).
-func (f Filters) sortColumn() string {
-	for _, safeValue := range f.SortSafelist {
-		if f.Sort == safeValue {
-			return strings.TrimPrefix(f.Sort, "-")
-		}
+	// Check that every key of the sort parameter matches a value in the safelist, and that no
+	// column is used twice.
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(f.Sort, ",") {
+		v.Check(validator.In(key, f.SortSafelist...), "sort", "invalid sort value "+key)
+		column := strings.TrimPrefix(key, "-")
+		v.Check(!seen[column], "sort", "must not sort by "+column+" more than once")
+		seen[column] = true
+	}
+	if !v.Valid() {
+		return
 	}
-	panic("unsafe sort parameter: " + f.Sort)
-}
 
-// Return the sort direction ("ASC" or "DESC") depending on the prefix character of the
-// Sort field.
-func (f Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
-		return "DESC"
+	v.Check(f.After == "" || f.Before == "", "after", "must not be used together with before")
+	for key, token := range map[string]string{"after": f.After, "before": f.Before} {
+		if token == "" {
+			continue
+		}
+		c, err := decodeCursor(token)
+		switch {
+		case err != nil:
+			v.AddError(key, "invalid cursor")
+		case c.Scope != scopeHash(f.Scope):
+			v.AddError(key, "cursor does not match the query, start again from the first page")
+		case c.Sort != f.Sort || len(c.Values) != len(f.sortKeys()):
+			v.AddError(key, "cursor does not match the sort order, start again from the first page")
+		default:
+			if _, err := f.cursorValues(c); err != nil {
+				v.AddError(key, "invalid cursor")
+			}
+		}
 	}
-	return "ASC"
 }
 
 func (f Filters) limit() int {
@@ -74,3 +102,265 @@ func (f Filters) limit() int {
 func (f Filters) offset() int {
 	return (f.Page - 1) * f.PageSize
 }
+
+// sortKey is one column of the sort order, by its safelist name.
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+// sortKeys returns the full sort order: the comma-separated keys of the Sort field, where a
+// leading hyphen means descending, followed by id as a tie-breaker unless it is already
+// included, so that the order is total and every row has a distinct position for the cursors.
+// It panics if a key is not in the safelist, which ValidateFilters checks first.
+func (f Filters) sortKeys() []sortKey {
+	var (
+		keys  []sortKey
+		hasID bool
+	)
+	for _, key := range strings.Split(f.Sort, ",") {
+		if !validator.In(key, f.SortSafelist...) {
+			panic("unsafe sort parameter: " + key)
+		}
+		column := strings.TrimPrefix(key, "-")
+		keys = append(keys, sortKey{column: column, desc: strings.HasPrefix(key, "-")})
+		hasID = hasID || column == "id"
+	}
+	if !hasID {
+		keys = append(keys, sortKey{column: "id"})
+	}
+	return keys
+}
+
+// cursorMode reports whether the list is paged by cursor rather than by page number.
+func (f Filters) cursorMode() bool {
+	return f.After != "" || f.Before != ""
+}
+
+// orderBy returns the ORDER BY clause for the sort keys. columns maps the safelist names to
+// SQL expressions. When paging backwards the order is reversed, and keysetPage restores it.
+func (f Filters) orderBy(columns map[string]string) string {
+	var parts []string
+	for _, key := range f.sortKeys() {
+		desc := key.desc != (f.Before != "")
+		direction := "ASC"
+		if desc {
+			direction = "DESC"
+		}
+		parts = append(parts, columns[key.column]+" "+direction)
+	}
+	return "ORDER BY " + strings.Join(parts, ", ")
+}
+
+// keyset returns the condition selecting the rows after (or before) the cursor, or an empty
+// string in page mode. For keys k1, k2 and cursor values v1, v2 it is
+// (k1 > v1) OR (k1 = v1 AND k2 > v2), with < for descending keys, so any mix of directions
+// works.
+func (f Filters) keyset(columns map[string]string, a *sqlArgs) string {
+	token := f.After
+	if token == "" {
+		token = f.Before
+	}
+	if token == "" {
+		return ""
+	}
+	// ValidateFilters has already checked the cursor.
+	c, _ := decodeCursor(token)
+	values, _ := f.cursorValues(c)
+
+	var (
+		alternatives []string
+		equal        []string
+	)
+	for i, key := range f.sortKeys() {
+		column := columns[key.column]
+		value := a.add(values[i])
+
+		op := ">"
+		if key.desc != (f.Before != "") {
+			op = "<"
+		}
+		alternatives = append(alternatives, "("+strings.Join(append(equal, column+" "+op+" "+value), " AND ")+")")
+		equal = append(equal, column+" = "+value)
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// pageLimit returns the LIMIT and OFFSET clause. In cursor mode one extra row is fetched to
+// find out whether there is another page.
+func (f Filters) pageLimit(a *sqlArgs) string {
+	if f.cursorMode() {
+		return "LIMIT " + a.add(f.limit()+1)
+	}
+	return "LIMIT " + a.add(f.limit()) + " OFFSET " + a.add(f.offset())
+}
+
+// sortable is implemented by the rows of lists that support cursor pagination.
+type sortable interface {
+	// sortValue returns the value of the row for the sort key with the given safelist name.
+	sortValue(column string) interface{}
+}
+
+// cursor is the content of an opaque pagination cursor: the hash of the list scope and the
+// sort it was created for, and the sort key values of the row it points at.
+type cursor struct {
+	Scope  string        `json:"q"`
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// scopeHash returns the short hash of a list scope that is kept in its cursors.
+func scopeHash(scope string) string {
+	sum := sha256.Sum256([]byte(scope))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// decimalRX matches a number in the JSON syntax.
+var decimalRX = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// sortKeyKind is the type of the values of a sort key.
+type sortKeyKind int
+
+const (
+	numberKey sortKeyKind = iota
+	integerKey
+	textKey
+	timeKey
+)
+
+// sortKeyKinds holds the type of every sort key that is not a number. Numbers come either as
+// JSON numbers or, for money amounts, as decimal strings.
+var sortKeyKinds = map[string]sortKeyKind{
+	"id":             integerKey,
+	"stock_quantity": integerKey,
+	"page_count":     integerKey,
+	"title":          textKey,
+	"author":         textKey,
+	"created_at":     timeKey,
+}
+
+// cursorValues checks that every cursor value has the type of its sort key, so that a forged
+// cursor is rejected as invalid instead of failing in the database, and returns the values
+// to compare the sort keys with.
+func (f Filters) cursorValues(c cursor) ([]interface{}, error) {
+	keys := f.sortKeys()
+	if len(c.Values) != len(keys) {
+		return nil, errInvalidCursor
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value := c.Values[i]
+		text, isText := value.(string)
+		number, isNumber := value.(json.Number)
+
+		switch sortKeyKinds[key.column] {
+		case textKey:
+			if !isText {
+				return nil, errInvalidCursor
+			}
+		case timeKey:
+			t, err := time.Parse(time.RFC3339Nano, text)
+			if !isText || err != nil {
+				return nil, errInvalidCursor
+			}
+			value = t
+		case integerKey:
+			n, err := number.Int64()
+			if !isNumber || err != nil {
+				return nil, errInvalidCursor
+			}
+			value = n
+		default:
+			if isNumber {
+				text = number.String()
+			}
+			// ParseFloat also accepts forms such as "Inf" and "0x1p-2" that the database
+			// doesn't, so the plain decimal syntax is checked first.
+			if !(isText || isNumber) || !decimalRX.MatchString(text) {
+				return nil, errInvalidCursor
+			}
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, errInvalidCursor
+			}
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+func decodeCursor(token string) (cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor{}, errInvalidCursor
+	}
+	// Numbers are kept as json.Number so that IDs and prices reach the database unchanged.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var c cursor
+	if err = dec.Decode(&c); err != nil {
+		return cursor{}, errInvalidCursor
+	}
+	for _, value := range c.Values {
+		switch value.(type) {
+		case string, json.Number:
+		default:
+			return cursor{}, errInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+// cursorFor returns the cursor pointing at the row.
+func (f Filters) cursorFor(row sortable) string {
+	c := cursor{Scope: scopeHash(f.Scope), Sort: f.Sort}
+	for _, key := range f.sortKeys() {
+		c.Values = append(c.Values, row.sortValue(key.column))
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// keysetPage finishes a page fetched with orderBy, keyset and pageLimit: it drops the extra
+// row, restores the order when paging backwards and fills in the metadata, including the
+// cursors of the neighbouring pages. totalRecords is only used in page mode.
+func keysetPage[T sortable](f Filters, rows []T, totalRecords int) ([]T, Metadata) {
+	if !f.cursorMode() {
+		metadata := calculateMetadata(totalRecords, f.Page, f.PageSize)
+		if len(rows) > 0 {
+			if f.Page > 1 {
+				metadata.PrevCursor = f.cursorFor(rows[0])
+			}
+			if f.Page < metadata.LastPage {
+				metadata.NextCursor = f.cursorFor(rows[len(rows)-1])
+			}
+		}
+		return rows, metadata
+	}
+
+	more := len(rows) > f.PageSize
+	if more {
+		rows = rows[:f.PageSize]
+	}
+	if f.Before != "" {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	metadata := Metadata{PageSize: f.PageSize}
+	if len(rows) > 0 {
+		// Paging forwards, there is always a previous page: the one the cursor came from.
+		// Paging backwards, the same holds for the next page.
+		if f.After != "" || more {
+			metadata.PrevCursor = f.cursorFor(rows[0])
+		}
+		if f.Before != "" || more {
+			metadata.NextCursor = f.cursorFor(rows[len(rows)-1])
+		}
+	}
+	return rows, metadata
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+type (
+	// Impersonation describes an authentication token that a staff user obtained to act as
+	// another user.
+	Impersonation struct {
+		StaffUserID  int64 `json:"staff_user_id"`
+		TargetUserID int64 `json:"target_user_id"`
+		ReadOnly     bool  `json:"read_only"`
+	}
+
+	// ImpersonationAuditEntry is a single request made under impersonation.
+	ImpersonationAuditEntry struct {
+		StaffUserID  int64
+		TargetUserID int64
+		Method       string
+		Path         string
+		Status       int
+	}
+
+	// ImpersonationModel struct wraps a sql.DB connection pool and allows us to work with the
+	// impersonations and impersonation_audit tables.
+	ImpersonationModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+	}
+)
+
+// Insert marks an existing authentication token as an impersonation token issued to a staff
+// user.
+func (m ImpersonationModel) Insert(token *Token, staffUserID int64, readOnly bool) error {
+	query := `
+		INSERT INTO impersonations (token_hash, staff_user_id, read_only)
+		VALUES ($1, $2, $3)
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, token.Hash, staffUserID, readOnly)
+	return err
+}
+
+// InsertAudit records a request made under impersonation.
+func (m ImpersonationModel) InsertAudit(entry *ImpersonationAuditEntry) error {
+	query := `
+		INSERT INTO impersonation_audit (staff_user_id, target_user_id, method, path, status)
+		VALUES ($1, $2, $3, $4, $5)
+		`
+
+	args := []interface{}{entry.StaffUserID, entry.TargetUserID, entry.Method, entry.Path, entry.Status}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidISBN возвращается, если ISBN имеет неверный формат или контрольную цифру
+var ErrInvalidISBN = errors.New("invalid ISBN")
+
+// NormalizeISBN проверяет ISBN-10 или ISBN-13 (с дефисами или пробелами или без них) и
+// возвращает его в виде ISBN-13 из одних цифр. Так одна и та же книга всегда хранится под
+// одним ISBN, независимо от того, в каком виде его ввели.
+func NormalizeISBN(isbn string) (string, error) {
+	isbn = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+
+	switch len(isbn) {
+	case 10:
+		sum := 0
+		for i, c := range isbn {
+			var digit int
+			switch {
+			case c >= '0' && c <= '9':
+				digit = int(c - '0')
+			case c == 'X' && i == 9:
+				digit = 10
+			default:
+				return "", ErrInvalidISBN
+			}
+			sum += digit * (10 - i)
+		}
+		if sum%11 != 0 {
+			return "", ErrInvalidISBN
+		}
+		// ISBN-10 переводится в ISBN-13 с префиксом 978 и пересчитанной контрольной цифрой.
+		isbn13 := "978" + isbn[:9]
+		return isbn13 + string(isbn13CheckDigit(isbn13)), nil
+
+	case 13:
+		for _, c := range isbn {
+			if c < '0' || c > '9' {
+				return "", ErrInvalidISBN
+			}
+		}
+		if isbn13CheckDigit(isbn[:12]) != isbn[12] {
+			return "", ErrInvalidISBN
+		}
+		return isbn, nil
+	}
+
+	return "", ErrInvalidISBN
+}
+
+// isbn13CheckDigit вычисляет контрольную цифру для первых 12 цифр ISBN-13
+func isbn13CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		digit := int(digits[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
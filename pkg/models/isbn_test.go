@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		name  string
+		isbn  string
+		want  string
+		error error
+	}{
+		{"isbn13", "9780306406157", "9780306406157", nil},
+		{"isbn13 with hyphens", "978-0-306-40615-7", "9780306406157", nil},
+		{"isbn13 with spaces", "978 0 306 40615 7", "9780306406157", nil},
+		{"isbn10", "0306406152", "9780306406157", nil},
+		{"isbn10 with hyphens", "0-306-40615-2", "9780306406157", nil},
+		{"isbn10 with X check digit", "0-8044-2957-X", "9780804429573", nil},
+		{"isbn10 with lowercase x", "080442957x", "9780804429573", nil},
+		{"isbn13 bad checksum", "9780306406158", "", ErrInvalidISBN},
+		{"isbn10 bad checksum", "0306406153", "", ErrInvalidISBN},
+		{"X not in last position", "03064X6152", "", ErrInvalidISBN},
+		{"isbn13 with letter", "978030640615X", "", ErrInvalidISBN},
+		{"too short", "030640615", "", ErrInvalidISBN},
+		{"too long", "97803064061570", "", ErrInvalidISBN},
+		{"empty", "", "", ErrInvalidISBN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeISBN(tt.isbn)
+			if !errors.Is(err, tt.error) {
+				t.Fatalf("NormalizeISBN(%q) error = %v, want %v", tt.isbn, err, tt.error)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeISBN(%q) = %q, want %q", tt.isbn, got, tt.want)
+			}
+		})
+	}
+}
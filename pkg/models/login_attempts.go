@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+type (
+	// LoginFailures summarises the failed login attempts recorded for a single email address
+	// or client IP within a time window.
+	LoginFailures struct {
+		Count int
+		Last  time.Time
+	}
+
+	// LoginAttemptModel struct wraps a sql.DB connection pool and allows us to work with the
+	// login_attempts table, which stores failed authentication attempts.
+	LoginAttemptModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+	}
+)
+
+// Insert records a login attempt for the given email address and client IP and returns its ID.
+// Attempts are recorded before the password is checked and count as failures until the login
+// succeeds, so that parallel guesses see each other. Note that the email is stored as provided
+// by the client, whether or not a matching user exists, so that the lockout behaviour is the
+// same for known and unknown accounts.
+func (m LoginAttemptModel) Insert(email, ip string) (int64, error) {
+	query := `
+		INSERT INTO login_attempts (email, ip)
+		VALUES ($1, $2)
+		RETURNING id
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var id int64
+	err := m.DB.QueryRowContext(ctx, query, email, ip).Scan(&id)
+	return id, err
+}
+
+// Delete removes a single attempt. It is used for attempts that were refused before the
+// password was checked, so that retrying during a lockout doesn't extend it.
+func (m LoginAttemptModel) Delete(id int64) error {
+	query := `
+		DELETE FROM login_attempts
+		WHERE id = $1
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// FailuresForEmail returns the failed attempts recorded for the email address from the client
+// IP since the provided time and before the attempt with the given ID. The IP is part of the
+// key so that guessing from one address can't lock the account for everyone else.
+func (m LoginAttemptModel) FailuresForEmail(email, ip string, since time.Time, before int64) (LoginFailures, error) {
+	query := `
+		SELECT count(*), max(created_at)
+		FROM login_attempts
+		WHERE email = $1 AND ip = $2 AND created_at > $3 AND id < $4
+		`
+
+	return m.failures(query, email, ip, since, before)
+}
+
+// FailuresForIP returns the failed attempts recorded for the client IP since the provided time
+// and before the attempt with the given ID.
+func (m LoginAttemptModel) FailuresForIP(ip string, since time.Time, before int64) (LoginFailures, error) {
+	query := `
+		SELECT count(*), max(created_at)
+		FROM login_attempts
+		WHERE ip = $1 AND created_at > $2 AND id < $3
+		`
+
+	return m.failures(query, ip, since, before)
+}
+
+func (m LoginAttemptModel) failures(query string, args ...interface{}) (LoginFailures, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var (
+		failures LoginFailures
+		last     sql.NullTime
+	)
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&failures.Count, &last)
+	if err != nil {
+		return LoginFailures{}, err
+	}
+	failures.Last = last.Time
+
+	return failures, nil
+}
+
+// DeleteAllForEmail removes every failed attempt recorded for the email address. It is used
+// after a successful login and when staff lift a lockout.
+func (m LoginAttemptModel) DeleteAllForEmail(email string) error {
+	query := `
+		DELETE FROM login_attempts
+		WHERE email = $1
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, email)
+	return err
+}
+
+// DeleteBefore deletes the failed attempts recorded before the provided time and returns the
+// number of deleted rows.
+func (m LoginAttemptModel) DeleteBefore(before time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_attempts
+		WHERE created_at < $1
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
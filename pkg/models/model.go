@@ -7,13 +7,24 @@ import (
 )
 
 type Models struct {
-	Books       BookModel
-	Users       UserModel
-	Tokens      TokenModel
-	Permissions PermissionModel
-	Purchase    PurchaseModel
-	Comment     CommentModel
-	Rating      RatingModel
+	Books          BookModel
+	Authors        AuthorModel
+	Categories     CategoryModel
+	Editions       EditionModel
+	Series         SeriesModel
+	Prices         PriceModel
+	PromoCodes     PromoCodeModel
+	Currencies     CurrencyModel
+	Translations   TranslationModel
+	Users          UserModel
+	Tokens         TokenModel
+	Permissions    PermissionModel
+	Purchase       PurchaseModel
+	Comment        CommentModel
+	Rating         RatingModel
+	LoginAttempts  LoginAttemptModel
+	EmailChanges   EmailChangeModel
+	Impersonations ImpersonationModel
 }
 
 func NewModels(db *sql.DB) Models {
@@ -25,6 +36,46 @@ func NewModels(db *sql.DB) Models {
 			InfoLog:  infoLog,
 			ErrorLog: errorLog,
 		},
+		Authors: AuthorModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
+		Categories: CategoryModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
+		Editions: EditionModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
+		Series: SeriesModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
+		Prices: PriceModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
+		PromoCodes: PromoCodeModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
+		Currencies: CurrencyModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
+		Translations: TranslationModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
 		Users: UserModel{
 			DB:       db,
 			InfoLog:  infoLog,
@@ -55,5 +106,20 @@ func NewModels(db *sql.DB) Models {
 			InfoLog:  infoLog,
 			ErrorLog: errorLog,
 		},
+		LoginAttempts: LoginAttemptModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
+		EmailChanges: EmailChangeModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
+		Impersonations: ImpersonationModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		},
 	}
 }
@@ -0,0 +1,320 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+)
+
+// BaseCurrency — валюта, в которой магазин хранит цены и суммы покупок
+const BaseCurrency = "USD"
+
+// ErrInvalidMoney возвращается, если сумму нельзя разобрать как число с не более чем двумя
+// знаками после точки
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// Money — денежная сумма в минимальных единицах валюты (центах), чтобы цены вроде 12.99
+// хранились и складывались без ошибок округления. В JSON сумма передается строкой:
+// {"amount": "12.99", "currency": "USD"}. При чтении также принимаются число 12.99 и
+// строка "12.99" — тогда используется BaseCurrency.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney возвращает сумму amount центов в базовой валюте
+func NewMoney(amount int64) Money {
+	return Money{Amount: amount, Currency: BaseCurrency}
+}
+
+// errTooPrecise возвращается parseHundredths, если в числе больше двух знаков после точки
+var errTooPrecise = errors.New("must have at most two decimal places")
+
+// parseHundredths разбирает десятичную запись вида "12.99" или "-3.5" в сотых долях.
+// Нули после второго знака после точки допускаются, другие цифры — нет.
+func parseHundredths(s string) (int64, error) {
+	str := strings.TrimSpace(s)
+	negative := strings.HasPrefix(str, "-")
+	if negative {
+		str = str[1:]
+	}
+
+	whole, frac, _ := strings.Cut(str, ".")
+	if whole == "" || strings.Trim(whole, "0123456789") != "" || strings.Trim(frac, "0123456789") != "" {
+		return 0, strconv.ErrSyntax
+	}
+	if len(frac) > 2 {
+		if strings.Trim(frac[2:], "0") != "" {
+			return 0, errTooPrecise
+		}
+		frac = frac[:2]
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	n, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		n = -n
+	}
+	return n, nil
+}
+
+// formatHundredths возвращает число сотых долей n в десятичной записи с двумя знаками после
+// точки
+func formatHundredths(n int64) string {
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, n/100, n%100)
+}
+
+// ParseMoney разбирает десятичную запись суммы вида "12.99" или "-3.5" в валюте currency.
+// Нули после второго знака после точки допускаются, другие цифры — нет.
+func ParseMoney(s, currency string) (Money, error) {
+	amount, err := parseHundredths(s)
+	if err != nil {
+		if errors.Is(err, errTooPrecise) {
+			return Money{}, fmt.Errorf("%w %q: %v", ErrInvalidMoney, s, err)
+		}
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidMoney, s)
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// String возвращает сумму в десятичной записи с двумя знаками после точки, без валюты
+func (m Money) String() string {
+	return formatHundredths(m.Amount)
+}
+
+// Add возвращает сумму m и other. Складывать можно только суммы в одной валюте.
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}
+}
+
+// Sub возвращает разность m и other в той же валюте
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}
+}
+
+// Mul возвращает сумму, умноженную на количество n
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Percent возвращает долю суммы в basisPoints сотых долях процента (1550 — это 15.5%),
+// округленную до цента в большую сторону при половине
+func (m Money) Percent(basisPoints int64) Money {
+	amount := m.Amount * basisPoints
+	if amount < 0 {
+		return Money{Amount: (amount - 5000) / 10000, Currency: m.Currency}
+	}
+	return Money{Amount: (amount + 5000) / 10000, Currency: m.Currency}
+}
+
+// Less сообщает, меньше ли m, чем other
+func (m Money) Less(other Money) bool {
+	m.mustMatch(other)
+	return m.Amount < other.Amount
+}
+
+// Min возвращает меньшую из двух сумм
+func (m Money) Min(other Money) Money {
+	if other.Less(m) {
+		return other
+	}
+	return m
+}
+
+// Equal сообщает, равны ли суммы и их валюты
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && m.Currency == other.Currency
+}
+
+// IsZero сообщает, равна ли сумма нулю
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative сообщает, отрицательна ли сумма
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// mustMatch паникует, если валюты сумм различаются: смешивание валют — ошибка в коде, а не
+// во входных данных
+func (m Money) mustMatch(other Money) {
+	if m.Currency != other.Currency {
+		panic(fmt.Sprintf("models: money currency mismatch: %s and %s", m.Currency, other.Currency))
+	}
+}
+
+// moneyJSON — представление Money в JSON
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON кодирует сумму как объект со строковой суммой, чтобы клиенты не теряли
+// точность при разборе
+func (m Money) MarshalJSON() ([]byte, error) {
+	currency := m.Currency
+	if currency == "" {
+		currency = BaseCurrency
+	}
+	return json.Marshal(moneyJSON{Amount: m.String(), Currency: currency})
+}
+
+// UnmarshalJSON принимает объект {"amount": "12.99", "currency": "USD"}, строку "12.99" или
+// число 12.99. Число разбирается по исходной записи, без преобразования в float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case bytes.HasPrefix(data, []byte("{")):
+		var input moneyJSON
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&input); err != nil {
+			return err
+		}
+		currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+		if currency == "" {
+			currency = BaseCurrency
+		}
+		money, err := ParseMoney(input.Amount, currency)
+		if err != nil {
+			return err
+		}
+		*m = money
+		return nil
+	case bytes.HasPrefix(data, []byte(`"`)):
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		money, err := ParseMoney(s, BaseCurrency)
+		if err != nil {
+			return err
+		}
+		*m = money
+		return nil
+	default:
+		// Экспоненциальная запись не поддерживается, ParseMoney вернет ошибку
+		money, err := ParseMoney(string(data), BaseCurrency)
+		if err != nil {
+			return err
+		}
+		*m = money
+		return nil
+	}
+}
+
+// Value передает сумму в базу данных десятичной строкой для столбцов NUMERIC
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan считывает сумму из столбца NUMERIC. Суммы в базе хранятся в BaseCurrency. Для
+// столбцов, допускающих NULL, используется *Money.
+func (m *Money) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case []byte:
+		s = string(src)
+	case string:
+		s = src
+	case int64:
+		*m = NewMoney(src * 100)
+		return nil
+	default:
+		return fmt.Errorf("models: cannot scan %T into Money", src)
+	}
+
+	money, err := ParseMoney(s, BaseCurrency)
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
+
+// ErrInvalidPercent возвращается, если процент нельзя разобрать как число с не более чем
+// двумя знаками после точки
+var ErrInvalidPercent = errors.New("invalid percent")
+
+// Percent — доля в сотых долях процента (basis points): 1550 — это 15.5%. Как и Money,
+// хранится целым числом и в JSON передается строкой "15.50"; при чтении принимаются также
+// число 15.5 и строка "15.5".
+type Percent int64
+
+// ParsePercent разбирает десятичную запись процента вида "15.5" с не более чем двумя знаками
+// после точки
+func ParsePercent(s string) (Percent, error) {
+	n, err := parseHundredths(s)
+	if err != nil {
+		if errors.Is(err, errTooPrecise) {
+			return 0, fmt.Errorf("%w %q: %v", ErrInvalidPercent, s, err)
+		}
+		return 0, fmt.Errorf("%w %q", ErrInvalidPercent, s)
+	}
+	return Percent(n), nil
+}
+
+// String возвращает процент в десятичной записи с двумя знаками после точки, без знака %
+func (p Percent) String() string {
+	return formatHundredths(int64(p))
+}
+
+// MarshalJSON кодирует процент строкой, как сумму в Money
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON принимает строку "15.5" или число 15.5. Число разбирается по исходной записи,
+// без преобразования в float64.
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(data)
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	percent, err := ParsePercent(s)
+	if err != nil {
+		return err
+	}
+	*p = percent
+	return nil
+}
+
+// Value передает процент в базу данных десятичной строкой для столбцов NUMERIC
+func (p Percent) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+// validatePrice проверяет, что сумма неотрицательна и указана в базовой валюте. Сумма без
+// валюты (например, не переданная клиентом) считается суммой в базовой валюте.
+func validatePrice(v *validator.Validator, price *Money, key string) {
+	if price.Currency == "" {
+		price.Currency = BaseCurrency
+	}
+	v.Check(!price.IsNegative(), key, "must not be negative")
+	v.Check(price.Currency == BaseCurrency, key, "must be in "+BaseCurrency)
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestParseHundredths(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  int64
+		error error
+	}{
+		{"12.99", 1299, nil},
+		{"12", 1200, nil},
+		{"12.", 1200, nil},
+		{"12.5", 1250, nil},
+		{"0.05", 5, nil},
+		{"-0.5", -50, nil},
+		{"-3.25", -325, nil},
+		{" 7.10 ", 710, nil},
+		{"1.500", 150, nil},
+		{"1.999", 0, errTooPrecise},
+		{"0.001", 0, errTooPrecise},
+		{"1e3", 0, strconv.ErrSyntax},
+		{".5", 0, strconv.ErrSyntax},
+		{"-", 0, strconv.ErrSyntax},
+		{"+1", 0, strconv.ErrSyntax},
+		{"1.2.3", 0, strconv.ErrSyntax},
+		{"", 0, strconv.ErrSyntax},
+		{"99999999999999999999", 0, strconv.ErrRange},
+	}
+
+	for _, tt := range tests {
+		got, err := parseHundredths(tt.in)
+		if !errors.Is(err, tt.error) {
+			t.Errorf("parseHundredths(%q) error = %v, want %v", tt.in, err, tt.error)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseHundredths(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyPercent(t *testing.T) {
+	tests := []struct {
+		amount      int64
+		basisPoints int64
+		want        int64
+	}{
+		{1000, 1550, 155},
+		{1299, 1000, 130},
+		{1, 5000, 1},
+		{1, 4999, 0},
+		{3, 5000, 2},
+		{1000, 0, 0},
+		{0, 1550, 0},
+		// Negative amounts are rounded away from zero at the half, like positive ones.
+		{-1000, 1550, -155},
+		{-1299, 1000, -130},
+		{-1, 5000, -1},
+		{-1, 4999, 0},
+		{-3, 5000, -2},
+	}
+
+	for _, tt := range tests {
+		got := NewMoney(tt.amount).Percent(tt.basisPoints)
+		if want := NewMoney(tt.want); !got.Equal(want) {
+			t.Errorf("NewMoney(%d).Percent(%d) = %d, want %d", tt.amount, tt.basisPoints, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		want  Money
+		error error
+	}{
+		{"object", `{"amount": "12.99", "currency": "USD"}`, Money{Amount: 1299, Currency: "USD"}, nil},
+		{"object without currency", `{"amount": "12.99"}`, Money{Amount: 1299, Currency: BaseCurrency}, nil},
+		{"object with lowercase currency", `{"amount": "5", "currency": " eur "}`, Money{Amount: 500, Currency: "EUR"}, nil},
+		{"object with too precise amount", `{"amount": "1.999"}`, Money{}, ErrInvalidMoney},
+		{"string", `"12.99"`, Money{Amount: 1299, Currency: BaseCurrency}, nil},
+		{"negative string", `"-0.5"`, Money{Amount: -50, Currency: BaseCurrency}, nil},
+		{"invalid string", `"twelve"`, Money{}, ErrInvalidMoney},
+		{"number", `12.99`, Money{Amount: 1299, Currency: BaseCurrency}, nil},
+		{"number is not rounded through float64", `0.29`, Money{Amount: 29, Currency: BaseCurrency}, nil},
+		{"number in exponent form", `1e3`, Money{}, ErrInvalidMoney},
+		{"null", `null`, Money{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := got.UnmarshalJSON([]byte(tt.json))
+			if !errors.Is(err, tt.error) {
+				t.Fatalf("UnmarshalJSON(%s) error = %v, want %v", tt.json, err, tt.error)
+			}
+			if got != tt.want {
+				t.Errorf("UnmarshalJSON(%s) = %+v, want %+v", tt.json, got, tt.want)
+			}
+		})
+	}
+
+	var m Money
+	if err := m.UnmarshalJSON([]byte(`{"amount": "1", "cents": 100}`)); err == nil {
+		t.Errorf("UnmarshalJSON accepted an object with an unknown field")
+	}
+}
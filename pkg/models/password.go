@@ -0,0 +1,139 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params holds the parameters used to derive Argon2id password hashes. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// PasswordParams are the parameters used for new password hashes. Stored hashes created with
+// different parameters, as well as legacy bcrypt hashes, still verify but are reported by
+// NeedsRehash() so that they can be upgraded on the next successful login.
+var PasswordParams = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// argon2idPrefix identifies hashes in the PHC string format produced by encodeArgon2id:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+//
+// Anything without this prefix is treated as a legacy bcrypt hash.
+const argon2idPrefix = "$argon2id$"
+
+var errInvalidHash = errors.New("invalid password hash format")
+
+// argon2Slots limits how many Argon2id keys are derived at the same time. Every derivation
+// allocates the full memory cost, so a burst of logins could otherwise exhaust the memory of
+// the server. Further hashes wait for a free slot.
+var argon2Slots = make(chan struct{}, 4)
+
+// SetMaxConcurrentHashes sets how many Argon2id keys may be derived at the same time. It must be
+// called before the first password is hashed or checked.
+func SetMaxConcurrentHashes(n int) {
+	argon2Slots = make(chan struct{}, n)
+}
+
+// deriveArgon2id derives the Argon2id key of the plaintext password once a slot is free.
+func deriveArgon2id(plaintextPassword string, salt []byte, params Argon2Params) []byte {
+	argon2Slots <- struct{}{}
+	defer func() { <-argon2Slots }()
+
+	return argon2.IDKey([]byte(plaintextPassword), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+}
+
+// hashArgon2id derives a new Argon2id hash for the plaintext password with a random salt and
+// returns it in the encoded format.
+func hashArgon2id(plaintextPassword string, params Argon2Params) ([]byte, error) {
+	salt := make([]byte, params.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	key := deriveArgon2id(plaintextPassword, salt, params)
+
+	encoded := fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return []byte(encoded), nil
+}
+
+// decodeArgon2id parses an encoded Argon2id hash, returning its parameters, salt and key.
+func decodeArgon2id(hash []byte) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2Params{}, nil, nil, errInvalidHash
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		return Argon2Params{}, nil, nil, errInvalidHash
+	}
+	if version != argon2.Version {
+		return Argon2Params{}, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	var params Argon2Params
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return Argon2Params{}, nil, nil, errInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, errInvalidHash
+	}
+	params.SaltLength = uint32(len(salt))
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2Params{}, nil, nil, errInvalidHash
+	}
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+// matchesArgon2id checks the plaintext password against an encoded Argon2id hash, using a
+// constant time comparison of the derived keys.
+func matchesArgon2id(plaintextPassword string, hash []byte) (bool, error) {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	otherKey := deriveArgon2id(plaintextPassword, salt, params)
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+// isArgon2id reports whether the stored hash uses the Argon2id format.
+func isArgon2id(hash []byte) bool {
+	return strings.HasPrefix(string(hash), argon2idPrefix)
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// Hit and miss counters for the permission cache, published on the expvar endpoint.
+var (
+	permissionCacheHits   = expvar.NewInt("permission_cache_hits")
+	permissionCacheMisses = expvar.NewInt("permission_cache_misses")
+)
+
+type permissionCacheEntry struct {
+	permissions Permissions
+	expires     time.Time
+}
+
+// PermissionCache is an in-process cache of the effective permissions of users, keyed by user
+// ID. Entries expire after the TTL and are dropped explicitly whenever permissions change.
+//
+// To stay correct under concurrent grants and revokes, every invalidation bumps a generation
+// counter. A reader notes the generation before querying the database and its result is only
+// stored if no invalidation happened in the meantime, so a value read before a change can never
+// be cached after it.
+type PermissionCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	generation uint64
+	entries    map[int64]permissionCacheEntry
+}
+
+// NewPermissionCache returns an empty PermissionCache whose entries live for ttl.
+func NewPermissionCache(ttl time.Duration) *PermissionCache {
+	return &PermissionCache{
+		ttl:     ttl,
+		entries: make(map[int64]permissionCacheEntry),
+	}
+}
+
+// get returns the cached permissions for the user and the current generation. The bool is
+// false if there is no unexpired entry.
+func (c *PermissionCache) get(userID int64) (Permissions, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, userID)
+		permissionCacheMisses.Add(1)
+		return nil, c.generation, false
+	}
+
+	permissionCacheHits.Add(1)
+	return entry.permissions, c.generation, true
+}
+
+// set stores the permissions for the user, unless the cache was invalidated since generation
+// was read.
+func (c *PermissionCache) set(userID int64, generation uint64, permissions Permissions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	c.entries[userID] = permissionCacheEntry{
+		permissions: permissions,
+		expires:     time.Now().Add(c.ttl),
+	}
+}
+
+// Invalidate drops the cached permissions of the user.
+func (c *PermissionCache) Invalidate(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	delete(c.entries, userID)
+}
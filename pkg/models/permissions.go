@@ -27,10 +27,34 @@ type PermissionModel struct {
 	DB       *sql.DB
 	InfoLog  *log.Logger
 	ErrorLog *log.Logger
+	// Cache holds recently looked up permissions. If it is nil, every lookup goes to the
+	// database.
+	Cache *PermissionCache
 }
 
-// GetAllForUser returns all permission codes for a specific user in a Permissions slice.
+// GetAllForUser returns all permission codes for a specific user in a Permissions slice,
+// served from the cache when possible.
 func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
+	if m.Cache == nil {
+		return m.getAllForUser(userID)
+	}
+
+	permissions, generation, ok := m.Cache.get(userID)
+	if ok {
+		return permissions, nil
+	}
+
+	permissions, err := m.getAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Cache.set(userID, generation, permissions)
+	return permissions, nil
+}
+
+// getAllForUser loads the permission codes for a specific user from the database.
+func (m PermissionModel) getAllForUser(userID int64) (Permissions, error) {
 	query := `
 		SELECT permissions.code
 		FROM permissions
@@ -72,16 +96,86 @@ func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
 	return permissions, nil
 }
 
-// AddForUser adds the provided codes for a specific user.
+// GetAll returns every permission code that exists.
+func (m PermissionModel) GetAll() (Permissions, error) {
+	query := `
+		SELECT code
+		FROM permissions
+		ORDER BY code
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	var permissions Permissions
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// AddForUser adds the provided codes for a specific user. Codes the user already has are
+// skipped.
 func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
 	query := `
 		INSERT INTO users_permissions
 		SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+		ON CONFLICT DO NOTHING
 		`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	m.Invalidate(userID)
 	return err
 }
+
+// RemoveForUser removes the provided codes from a specific user.
+func (m PermissionModel) RemoveForUser(userID int64, codes ...string) error {
+	query := `
+		DELETE FROM users_permissions
+		USING permissions
+		WHERE users_permissions.permission_id = permissions.id
+			AND users_permissions.user_id = $1
+			AND permissions.code = ANY($2)
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	m.Invalidate(userID)
+	return err
+}
+
+// Invalidate drops the cached permissions of a specific user. It must be called after every
+// change to the user's permissions made outside of this model.
+func (m PermissionModel) Invalidate(userID int64) {
+	if m.Cache != nil {
+		m.Cache.Invalidate(userID)
+	}
+}
@@ -0,0 +1,402 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+)
+
+var (
+	// ErrScheduleOverlap возвращается, если новое изменение цены пересекается по времени с
+	// уже запланированным или действующим изменением того же издания
+	ErrScheduleOverlap = errors.New("scheduled price overlaps another one")
+
+	// ErrScheduleFinished возвращается при попытке отменить уже завершенное изменение цены
+	ErrScheduleFinished = errors.New("scheduled price has already finished")
+)
+
+// PriceChange — запись истории цен издания. OldPrice равен nil для цены, с которой издание
+// было создано.
+type PriceChange struct {
+	ID               int64     `json:"id"`
+	EditionID        int64     `json:"edition_id"`
+	Format           string    `json:"format"`
+	OldPrice         *Money    `json:"old_price"`
+	NewPrice         Money     `json:"new_price"`
+	ChangedBy        int64     `json:"changed_by,omitempty"`
+	ScheduledPriceID int64     `json:"scheduled_price_id,omitempty"`
+	ChangedAt        time.Time `json:"changed_at"`
+}
+
+// ScheduledPrice — запланированное изменение цены издания. Изменение с EndsAt — это акция:
+// после ее окончания возвращается прежняя цена. Изменение без EndsAt постоянное. EditionID
+// равен 0, если издание удалено.
+type ScheduledPrice struct {
+	ID        int64      `json:"id"`
+	EditionID int64      `json:"edition_id"`
+	Price     Money      `json:"price"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	Status    string     `json:"status"`
+	CreatedBy int64      `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// PriceModel обрабатывает историю цен и запланированные изменения цен
+type PriceModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// scheduledPriceColumns перечисляет столбцы изменения цены в порядке, в котором их считывает scanDest
+const scheduledPriceColumns = `id, COALESCE(edition_id, 0), price, starts_at, ends_at, status, COALESCE(created_by, 0), created_at`
+
+// scanDest возвращает указатели на поля изменения цены в порядке столбцов scheduledPriceColumns
+func (p *ScheduledPrice) scanDest() []interface{} {
+	return []interface{}{&p.ID, &p.EditionID, &p.Price, &p.StartsAt, &p.EndsAt, &p.Status, &p.CreatedBy, &p.CreatedAt}
+}
+
+// History возвращает историю цен всех изданий книги, включая удаленные, начиная с последних
+// изменений
+func (m PriceModel) History(bookID int64, filters Filters) ([]*PriceChange, Metadata, error) {
+	query := `
+    SELECT count(*) OVER(), id, edition_id, format, old_price, new_price, COALESCE(changed_by, 0),
+        COALESCE(scheduled_price_id, 0), changed_at
+    FROM price_history
+    WHERE book_id = $1
+    ORDER BY changed_at DESC, id DESC
+    LIMIT $2 OFFSET $3
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, bookID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	changes := []*PriceChange{}
+	for rows.Next() {
+		var change PriceChange
+		err := rows.Scan(
+			&totalRecords, &change.ID, &change.EditionID, &change.Format, &change.OldPrice, &change.NewPrice,
+			&change.ChangedBy, &change.ScheduledPriceID, &change.ChangedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		changes = append(changes, &change)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return changes, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Schedule планирует изменение цены. Пересечение с другим предстоящим или действующим
+// изменением того же издания возвращает ErrScheduleOverlap.
+func (m PriceModel) Schedule(price *ScheduledPrice) error {
+	query := `
+    INSERT INTO scheduled_prices (edition_id, price, starts_at, ends_at, created_by)
+    VALUES ($1, $2, $3, $4, NULLIF($5, 0))
+    RETURNING id, status, created_at
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{price.EditionID, price.Price, price.StartsAt, price.EndsAt, price.CreatedBy}
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&price.ID, &price.Status, &price.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), `violates exclusion constraint "scheduled_prices_no_overlap"`) {
+			return ErrScheduleOverlap
+		}
+		return err
+	}
+	return nil
+}
+
+// GetScheduled возвращает запланированное изменение цены по ID
+func (m PriceModel) GetScheduled(id int64) (*ScheduledPrice, error) {
+	query := `
+    SELECT ` + scheduledPriceColumns + `
+    FROM scheduled_prices
+    WHERE id = $1
+    `
+	var price ScheduledPrice
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(price.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &price, nil
+}
+
+// ScheduledForEdition возвращает все запланированные изменения цены издания, начиная с
+// самых поздних
+func (m PriceModel) ScheduledForEdition(editionID int64) ([]*ScheduledPrice, error) {
+	query := `
+    SELECT ` + scheduledPriceColumns + `
+    FROM scheduled_prices
+    WHERE edition_id = $1
+    ORDER BY starts_at DESC, id DESC
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, editionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []*ScheduledPrice{}
+	for rows.Next() {
+		var price ScheduledPrice
+		if err := rows.Scan(price.scanDest()...); err != nil {
+			return nil, err
+		}
+		prices = append(prices, &price)
+	}
+	return prices, rows.Err()
+}
+
+// Cancel отменяет запланированное изменение цены. Если акция уже идет, прежняя цена
+// возвращается сразу. Завершенные изменения отменить нельзя, для них возвращается
+// ErrScheduleFinished.
+func (m PriceModel) Cancel(price *ScheduledPrice) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	due, err := lockScheduledPrice(ctx, tx, `scheduled_prices.id = $1`, false, price.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	switch due.status {
+	case "pending":
+		_, err = tx.ExecContext(ctx, `UPDATE scheduled_prices SET status = 'cancelled' WHERE id = $1`, due.id)
+	case "active":
+		err = finishScheduledPrice(ctx, tx, due, "cancelled")
+	default:
+		return ErrScheduleFinished
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	price.Status = "cancelled"
+	return nil
+}
+
+// ApplyScheduled применяет наступившие изменения цен и завершает закончившиеся акции на
+// момент now. Каждое изменение применяется в своей транзакции, поэтому несколько экземпляров
+// приложения могут работать одновременно. Возвращается количество обработанных изменений.
+func (m PriceModel) ApplyScheduled(now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Изменения, время действия которых прошло, пока планировщик не работал, не применяются.
+	// Изменения удаленных изданий отменяются.
+	var count int64
+	for _, query := range []string{
+		`UPDATE scheduled_prices SET status = 'missed' WHERE status = 'pending' AND ends_at <= $1`,
+		`UPDATE scheduled_prices SET status = 'cancelled' WHERE status IN ('pending', 'active') AND edition_id IS NULL`,
+	} {
+		result, err := m.DB.ExecContext(ctx, query, now)
+		if err != nil {
+			return count, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return count, err
+		}
+		count += n
+	}
+
+	steps := []struct {
+		where string
+		apply func(context.Context, *sql.Tx, *duePrice) error
+	}{
+		{`scheduled_prices.status = 'active' AND scheduled_prices.ends_at <= $1`, func(ctx context.Context, tx *sql.Tx, due *duePrice) error {
+			return finishScheduledPrice(ctx, tx, due, "completed")
+		}},
+		{`scheduled_prices.status = 'pending' AND scheduled_prices.starts_at <= $1`, startScheduledPrice},
+	}
+
+	for _, step := range steps {
+		for {
+			applied, err := m.applyNext(ctx, step.where, now, step.apply)
+			if err != nil {
+				return count, err
+			}
+			if !applied {
+				break
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// applyNext блокирует одно изменение цены, подходящее под условие where, и применяет к нему
+// apply. Если таких изменений нет, возвращается false.
+func (m PriceModel) applyNext(ctx context.Context, where string, now time.Time, apply func(context.Context, *sql.Tx, *duePrice) error) (bool, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	due, err := lockScheduledPrice(ctx, tx, where+` ORDER BY scheduled_prices.starts_at LIMIT 1`, true, now)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err = apply(ctx, tx, due); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// duePrice — заблокированное изменение цены вместе с текущей ценой издания
+type duePrice struct {
+	id           int64
+	editionID    int64
+	price        Money
+	permanent    bool
+	status       string
+	restorePrice *Money
+	createdBy    int64
+	currentPrice Money
+}
+
+// lockScheduledPrice блокирует изменение цены, подходящее под условие where, и его издание.
+// Если skipLocked, строки, уже заблокированные другой транзакцией, пропускаются.
+func lockScheduledPrice(ctx context.Context, tx *sql.Tx, where string, skipLocked bool, args ...interface{}) (*duePrice, error) {
+	lock := "FOR UPDATE"
+	if skipLocked {
+		lock += " SKIP LOCKED"
+	}
+
+	query := `
+    SELECT scheduled_prices.id, scheduled_prices.edition_id, scheduled_prices.price,
+        scheduled_prices.ends_at IS NULL, scheduled_prices.status, scheduled_prices.restore_price,
+        COALESCE(scheduled_prices.created_by, 0), editions.price
+    FROM scheduled_prices
+    INNER JOIN editions ON editions.id = scheduled_prices.edition_id
+    WHERE ` + where + `
+    ` + lock + `
+    `
+	var due duePrice
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&due.id, &due.editionID, &due.price, &due.permanent, &due.status, &due.restorePrice,
+		&due.createdBy, &due.currentPrice,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &due, nil
+}
+
+// startScheduledPrice устанавливает запланированную цену и запоминает прежнюю, чтобы
+// вернуть ее после окончания акции
+func startScheduledPrice(ctx context.Context, tx *sql.Tx, due *duePrice) error {
+	status := "active"
+	if due.permanent {
+		status = "completed"
+	}
+
+	_, err := tx.ExecContext(ctx, `
+    UPDATE scheduled_prices
+    SET status = $1, restore_price = $2
+    WHERE id = $3
+    `, status, due.currentPrice, due.id)
+	if err != nil {
+		return err
+	}
+	return setEditionPrice(ctx, tx, due.editionID, due.currentPrice, due.price, due.createdBy, due.id)
+}
+
+// finishScheduledPrice завершает акцию со статусом status и возвращает прежнюю цену. Если
+// цену издания за время акции изменили вручную, она остается как есть.
+func finishScheduledPrice(ctx context.Context, tx *sql.Tx, due *duePrice, status string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE scheduled_prices SET status = $1 WHERE id = $2`, status, due.id)
+	if err != nil {
+		return err
+	}
+	if due.restorePrice == nil || !due.currentPrice.Equal(due.price) {
+		return nil
+	}
+	return setEditionPrice(ctx, tx, due.editionID, due.currentPrice, *due.restorePrice, due.createdBy, due.id)
+}
+
+// setEditionPrice меняет цену издания и записывает изменение в историю
+func setEditionPrice(ctx context.Context, tx *sql.Tx, editionID int64, oldPrice, newPrice Money, changedBy, scheduledPriceID int64) error {
+	_, err := tx.ExecContext(ctx, `
+    UPDATE editions
+    SET price = $1, updated_at = NOW(), version = version + 1
+    WHERE id = $2
+    `, newPrice, editionID)
+	if err != nil {
+		return err
+	}
+	return recordPriceChange(ctx, tx, editionID, &oldPrice, newPrice, changedBy, scheduledPriceID)
+}
+
+// recordPriceChange добавляет запись в историю цен. Книга и формат берутся из издания, чтобы
+// запись осталась в истории книги и после удаления издания. oldPrice равен nil для новых
+// изданий, changedBy и scheduledPriceID равны 0, если неизвестны.
+func recordPriceChange(ctx context.Context, tx *sql.Tx, editionID int64, oldPrice *Money, newPrice Money, changedBy, scheduledPriceID int64) error {
+	query := `
+    INSERT INTO price_history (edition_id, book_id, format, old_price, new_price, changed_by, scheduled_price_id)
+    SELECT id, book_id, format, $2::numeric, $3::numeric, NULLIF($4, 0), NULLIF($5, 0)
+    FROM editions
+    WHERE id = $1
+    `
+	_, err := tx.ExecContext(ctx, query, editionID, oldPrice, newPrice, changedBy, scheduledPriceID)
+	return err
+}
+
+// ValidateScheduledPrice проверяет запланированное изменение цены. Начало должно быть в
+// будущем, чтобы изменение не применилось задним числом.
+func ValidateScheduledPrice(v *validator.Validator, price *ScheduledPrice, now time.Time) {
+	validatePrice(v, &price.Price, "price")
+	v.Check(!price.StartsAt.IsZero(), "starts_at", "must be provided")
+	v.Check(price.StartsAt.After(now), "starts_at", "must be in the future")
+	if price.EndsAt != nil {
+		v.Check(price.EndsAt.After(price.StartsAt), "ends_at", "must be after starts_at")
+	}
+}
@@ -0,0 +1,394 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrDuplicatePromoCode возвращается при попытке сохранить уже существующий промокод
+	ErrDuplicatePromoCode = errors.New("duplicate promo code")
+
+	// PromoCodeRX проверяет формат промокода: латинские буквы, цифры, дефис и подчеркивание
+	PromoCodeRX = regexp.MustCompile("^[A-Za-z0-9_-]{3,32}$")
+)
+
+// PromoCodeError возвращается, если промокод нельзя применить к покупке. Reason объясняет
+// причину и показывается покупателю.
+type PromoCodeError struct {
+	Reason string
+}
+
+func (e *PromoCodeError) Error() string {
+	return "promo code " + e.Reason
+}
+
+// PromoCode — промокод на скидку в процентах (Kind "percent", задается Percent) или
+// фиксированной суммой (Kind "fixed", задается Amount). Нулевые MaxUses и MaxUsesPerUser
+// означают отсутствие ограничения. Если
+// списки BookIDs, AuthorIDs и CategoryIDs пусты, промокод действует на все книги, иначе на
+// книги, подходящие хотя бы под один из них.
+type PromoCode struct {
+	ID             int64      `json:"id"`
+	Code           string     `json:"code"`
+	Kind           string     `json:"kind"`
+	Percent        *Percent   `json:"percent,omitempty"`
+	Amount         *Money     `json:"amount,omitempty"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	MaxUses        int        `json:"max_uses"`
+	MaxUsesPerUser int        `json:"max_uses_per_user"`
+	MinOrder       Money      `json:"min_order"`
+	BookIDs        []int64    `json:"book_ids"`
+	AuthorIDs      []int64    `json:"author_ids"`
+	CategoryIDs    []int64    `json:"category_ids"`
+	Active         bool       `json:"active"`
+	UsedCount      int        `json:"used_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Version        int32      `json:"version"`
+
+	// value — значение столбца value, в котором хранится и процент, и сумма скидки. Его
+	// заполняет scanDest, а afterScan переносит в Percent или Amount в зависимости от Kind.
+	value Money
+}
+
+// PromoCodeModel обрабатывает операции с промокодами в базе данных
+type PromoCodeModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// promoCodeColumns перечисляет столбцы промокода в порядке, в котором их считывает scanDest
+const promoCodeColumns = `id, code, kind, value, starts_at, ends_at, max_uses, max_uses_per_user, min_order,
+        book_ids, author_ids, category_ids, active,
+        (SELECT COUNT(*) FROM purchases WHERE purchases.promo_code_id = promo_codes.id),
+        created_at, updated_at, version`
+
+// scanDest возвращает указатели на поля промокода в порядке столбцов promoCodeColumns
+func (p *PromoCode) scanDest() []interface{} {
+	return []interface{}{
+		&p.ID, &p.Code, &p.Kind, &p.value, &p.StartsAt, &p.EndsAt, &p.MaxUses, &p.MaxUsesPerUser, &p.MinOrder,
+		pq.Array(&p.BookIDs), pq.Array(&p.AuthorIDs), pq.Array(&p.CategoryIDs), &p.Active,
+		&p.UsedCount, &p.CreatedAt, &p.UpdatedAt, &p.Version,
+	}
+}
+
+// afterScan переносит считанное значение скидки в поле, соответствующее виду промокода
+func (p *PromoCode) afterScan() {
+	if p.Kind == "percent" {
+		percent := Percent(p.value.Amount)
+		p.Percent, p.Amount = &percent, nil
+		return
+	}
+	amount := p.value
+	p.Percent, p.Amount = nil, &amount
+}
+
+// dbValue возвращает значение столбца value: процент или сумму скидки
+func (p *PromoCode) dbValue() interface{} {
+	switch {
+	case p.Percent != nil:
+		return *p.Percent
+	case p.Amount != nil:
+		return *p.Amount
+	default:
+		return nil
+	}
+}
+
+// promoCodeSortColumns сопоставляет значения параметра sort списка промокодов выражениям SQL
+var promoCodeSortColumns = map[string]string{
+	"id":         "id",
+	"code":       "code",
+	"created_at": "created_at",
+}
+
+// Insert добавляет новый промокод
+func (m PromoCodeModel) Insert(promo *PromoCode) error {
+	query := `
+        INSERT INTO promo_codes (code, kind, value, starts_at, ends_at, max_uses, max_uses_per_user, min_order,
+            book_ids, author_ids, category_ids, active)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING id, created_at, updated_at, version
+    `
+	args := []interface{}{
+		promo.Code, promo.Kind, promo.dbValue(), promo.StartsAt, promo.EndsAt, promo.MaxUses, promo.MaxUsesPerUser,
+		promo.MinOrder, idArray(promo.BookIDs), idArray(promo.AuthorIDs), idArray(promo.CategoryIDs), promo.Active,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&promo.ID, &promo.CreatedAt, &promo.UpdatedAt, &promo.Version)
+	if err != nil {
+		if isDuplicatePromoCode(err) {
+			return ErrDuplicatePromoCode
+		}
+		return err
+	}
+	return nil
+}
+
+// Get возвращает промокод по ID
+func (m PromoCodeModel) Get(id int64) (*PromoCode, error) {
+	query := `
+    SELECT ` + promoCodeColumns + `
+    FROM promo_codes
+    WHERE id = $1
+    `
+	var promo PromoCode
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(promo.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	promo.afterScan()
+	return &promo, nil
+}
+
+// GetAll возвращает промокоды, содержащие code, с учетом пагинации и сортировки
+func (m PromoCodeModel) GetAll(code string, filters Filters) ([]*PromoCode, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), %s
+        FROM promo_codes
+        WHERE (code ILIKE $1 OR $1 = '')
+        %s
+        LIMIT $2 OFFSET $3
+        `, promoCodeColumns, filters.orderBy(promoCodeSortColumns))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, "%"+code+"%", filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	promos := []*PromoCode{}
+	for rows.Next() {
+		var promo PromoCode
+		if err := rows.Scan(append([]interface{}{&totalRecords}, promo.scanDest()...)...); err != nil {
+			return nil, Metadata{}, err
+		}
+		promo.afterScan()
+		promos = append(promos, &promo)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return promos, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Update сохраняет изменения промокода. Если запись была изменена или удалена с момента
+// чтения, возвращается ErrEditConflict.
+func (m PromoCodeModel) Update(promo *PromoCode) error {
+	query := `
+    UPDATE promo_codes
+    SET code = $1, kind = $2, value = $3, starts_at = $4, ends_at = $5, max_uses = $6, max_uses_per_user = $7,
+        min_order = $8, book_ids = $9, author_ids = $10, category_ids = $11, active = $12,
+        updated_at = NOW(), version = version + 1
+    WHERE id = $13 AND version = $14
+    RETURNING updated_at, version
+    `
+	args := []interface{}{
+		promo.Code, promo.Kind, promo.dbValue(), promo.StartsAt, promo.EndsAt, promo.MaxUses, promo.MaxUsesPerUser,
+		promo.MinOrder, idArray(promo.BookIDs), idArray(promo.AuthorIDs), idArray(promo.CategoryIDs), promo.Active,
+		promo.ID, promo.Version,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&promo.UpdatedAt, &promo.Version)
+	if err != nil {
+		switch {
+		case isDuplicatePromoCode(err):
+			return ErrDuplicatePromoCode
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete удаляет промокод. Покупки со скидкой по нему сохраняют сумму скидки.
+func (m PromoCodeModel) Delete(id int64) error {
+	query := `
+    DELETE FROM promo_codes
+    WHERE id = $1
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// redeemPromoCode проверяет, можно ли применить промокод code к покупке, и возвращает его ID
+// и сумму скидки. Строка промокода блокируется до конца транзакции, поэтому одновременные
+// покупки не могут превысить лимиты использования. Если промокод не подходит, возвращается
+// *PromoCodeError.
+func redeemPromoCode(ctx context.Context, tx *sql.Tx, code string, purchase *Purchase, subtotal Money, now time.Time) (int64, Money, error) {
+	query := `
+    SELECT id, kind, value, starts_at, ends_at, max_uses, max_uses_per_user, min_order, active,
+        cardinality(book_ids) + cardinality(author_ids) + cardinality(category_ids) = 0
+        OR $2 = ANY(book_ids)
+        OR EXISTS (
+            SELECT 1 FROM book_authors
+            WHERE book_authors.book_id = $2 AND book_authors.author_id = ANY(promo_codes.author_ids)
+        )
+        OR EXISTS (
+            SELECT 1 FROM book_categories
+            WHERE book_categories.book_id = $2 AND book_categories.category_id IN (` + descendantsOf("id = ANY(promo_codes.category_ids)") + `)
+        )
+    FROM promo_codes
+    WHERE code = $1
+    FOR UPDATE
+    `
+	var (
+		promo      PromoCode
+		applicable bool
+	)
+	err := tx.QueryRowContext(ctx, query, code, purchase.BookID).Scan(
+		&promo.ID, &promo.Kind, &promo.value, &promo.StartsAt, &promo.EndsAt, &promo.MaxUses,
+		&promo.MaxUsesPerUser, &promo.MinOrder, &promo.Active, &applicable,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, Money{}, &PromoCodeError{Reason: "does not exist"}
+		default:
+			return 0, Money{}, err
+		}
+	}
+	promo.afterScan()
+
+	var uses, userUses int
+	err = tx.QueryRowContext(ctx, `
+    SELECT COUNT(*), COUNT(*) FILTER (WHERE user_id = $2)
+    FROM purchases
+    WHERE promo_code_id = $1
+    `, promo.ID, purchase.UserID).Scan(&uses, &userUses)
+	if err != nil {
+		return 0, Money{}, err
+	}
+
+	switch {
+	case !promo.Active:
+		return 0, Money{}, &PromoCodeError{Reason: "is no longer active"}
+	case promo.StartsAt != nil && now.Before(*promo.StartsAt):
+		return 0, Money{}, &PromoCodeError{Reason: "is not valid yet"}
+	case promo.EndsAt != nil && !now.Before(*promo.EndsAt):
+		return 0, Money{}, &PromoCodeError{Reason: "has expired"}
+	case promo.MaxUses > 0 && uses >= promo.MaxUses:
+		return 0, Money{}, &PromoCodeError{Reason: "has reached its usage limit"}
+	case promo.MaxUsesPerUser > 0 && userUses >= promo.MaxUsesPerUser:
+		return 0, Money{}, &PromoCodeError{Reason: "has already been used the maximum number of times"}
+	case subtotal.Less(promo.MinOrder):
+		return 0, Money{}, &PromoCodeError{Reason: fmt.Sprintf("requires an order of at least %s %s", promo.MinOrder, promo.MinOrder.Currency)}
+	case !applicable:
+		return 0, Money{}, &PromoCodeError{Reason: "does not apply to this book"}
+	}
+
+	return promo.ID, promo.discount(subtotal), nil
+}
+
+// discount возвращает скидку на сумму subtotal, округленную до цента. Скидка не может
+// превышать сумму заказа.
+func (p *PromoCode) discount(subtotal Money) Money {
+	if p.Percent != nil {
+		return subtotal.Percent(int64(*p.Percent)).Min(subtotal)
+	}
+	return p.Amount.Min(subtotal)
+}
+
+// ValidatePromoCode проверяет поля промокода
+func ValidatePromoCode(v *validator.Validator, promo *PromoCode) {
+	v.Check(validator.Matches(promo.Code, PromoCodeRX), "code", "must be 3 to 32 letters, digits, hyphens or underscores")
+	v.Check(validator.In(promo.Kind, "percent", "fixed"), "kind", "must be percent or fixed")
+	switch promo.Kind {
+	case "percent":
+		v.Check(promo.Amount == nil, "amount", "must not be set for a percent code")
+		if promo.Percent == nil {
+			v.AddError("percent", "must be provided")
+			break
+		}
+		v.Check(*promo.Percent > 0, "percent", "must be greater than zero")
+		v.Check(*promo.Percent <= 100_00, "percent", "must not be more than 100")
+	case "fixed":
+		v.Check(promo.Percent == nil, "percent", "must not be set for a fixed code")
+		if promo.Amount == nil {
+			v.AddError("amount", "must be provided")
+			break
+		}
+		validatePrice(v, promo.Amount, "amount")
+		v.Check(promo.Amount.Amount > 0, "amount", "must be greater than zero")
+		v.Check(promo.Amount.Amount < 100_000_000_00, "amount", "must be less than 100000000")
+	}
+	if promo.StartsAt != nil && promo.EndsAt != nil {
+		v.Check(promo.EndsAt.After(*promo.StartsAt), "ends_at", "must be after starts_at")
+	}
+	v.Check(promo.MaxUses >= 0, "max_uses", "must not be negative")
+	v.Check(promo.MaxUsesPerUser >= 0, "max_uses_per_user", "must not be negative")
+	validatePrice(v, &promo.MinOrder, "min_order")
+
+	for key, ids := range map[string][]int64{"book_ids": promo.BookIDs, "author_ids": promo.AuthorIDs, "category_ids": promo.CategoryIDs} {
+		for _, id := range ids {
+			v.Check(id > 0, key, "must contain valid ids")
+		}
+		v.Check(validator.Unique(idStrings(ids)), key, "must not contain duplicate values")
+	}
+}
+
+// idArray передает список ID как массив PostgreSQL. Nil передается как пустой массив, а не NULL.
+func idArray(ids []int64) interface{} {
+	if ids == nil {
+		ids = []int64{}
+	}
+	return pq.Array(ids)
+}
+
+// idStrings преобразует список ID в строки для проверки validator.Unique
+func idStrings(ids []int64) []string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = fmt.Sprint(id)
+	}
+	return values
+}
+
+// isDuplicatePromoCode сообщает, нарушает ли ошибка уникальность промокода
+func isDuplicatePromoCode(err error) bool {
+	return strings.Contains(err.Error(), `violates unique constraint "promo_codes_code_key"`)
+}
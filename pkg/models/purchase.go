@@ -3,18 +3,50 @@ package models
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 )
 
+// ErrOutOfStock возвращается, если на складе недостаточно экземпляров издания
+var ErrOutOfStock = errors.New("edition is out of stock")
+
 // Purchase представляет одну покупку книги пользователем.
 type Purchase struct {
-	ID         int64     `json:"id"`          // Уникальный идентификатор покупки
-	UserID     int64     `json:"user_id"`     // Идентификатор пользователя, совершившего покупку
-	BookID     int64     `json:"book_id"`     // Идентификатор купленной книги
-	Quantity   int       `json:"quantity"`    // Количество купленных экземпляров
-	TotalPrice float64   `json:"total_price"` // Общая цена покупки
-	CreatedAt  time.Time `json:"created_at"`  // Время создания записи о покупке
+	ID           int64     `json:"id"`                      // Уникальный идентификатор покупки
+	UserID       int64     `json:"user_id"`                 // Идентификатор пользователя, совершившего покупку
+	BookID       int64     `json:"book_id"`                 // Идентификатор купленной книги
+	EditionID    int64     `json:"edition_id"`              // Идентификатор купленного издания, 0 если издание удалено
+	Quantity     int       `json:"quantity"`                // Количество купленных экземпляров
+	TotalPrice   Money     `json:"total_price"`             // Общая цена покупки с учетом скидки в базовой валюте
+	Discount     Money     `json:"discount"`                // Скидка по промокоду
+	PromoCodeID  int64     `json:"promo_code_id,omitempty"` // Идентификатор примененного промокода
+	Currency     string    `json:"currency"`                // Валюта оплаты
+	ChargedTotal Money     `json:"charged_total"`           // Сумма, списанная в валюте оплаты
+	ExchangeRate string    `json:"exchange_rate"`           // Курс валюты оплаты на момент покупки
+	CreatedAt    time.Time `json:"created_at"`              // Время создания записи о покупке
+}
+
+// purchaseColumns перечисляет столбцы покупки в порядке, в котором их считывает scanDest
+const purchaseColumns = `id, user_id, book_id, COALESCE(edition_id, 0), quantity, total_price, discount,
+        COALESCE(promo_code_id, 0), currency, charged_total, exchange_rate, created_at`
+
+// scanDest возвращает указатели на поля покупки в порядке столбцов purchaseColumns. После
+// чтения нужно вызвать afterScan.
+func (p *Purchase) scanDest() []interface{} {
+	return []interface{}{
+		&p.ID, &p.UserID, &p.BookID, &p.EditionID, &p.Quantity, &p.TotalPrice, &p.Discount,
+		&p.PromoCodeID, &p.Currency, &p.ChargedTotal, &p.ExchangeRate, &p.CreatedAt,
+	}
+}
+
+// afterScan указывает валюту оплаты у списанной суммы и убирает лишние нули у курса
+func (p *Purchase) afterScan() {
+	rate := CurrencyRate{Currency: p.Currency, Rate: p.ExchangeRate}
+	rate.afterScan()
+	p.ChargedTotal.Currency = p.Currency
+	p.ExchangeRate = rate.Rate
 }
 
 type PurchaseModel struct {
@@ -23,21 +55,88 @@ type PurchaseModel struct {
 	ErrorLog *log.Logger
 }
 
-func (m *PurchaseModel) Insert(purchase *Purchase) error {
-	query := `
-    INSERT INTO purchases (user_id, book_id, quantity, total_price, created_at)
-    VALUES ($1, $2, $3, $4, NOW()) RETURNING id;
-    `
+// Buy оформляет покупку purchase.Quantity экземпляров издания purchase.EditionID по его
+// текущей цене. Списание со склада, применение промокода code (если он указан) и запись
+// покупки выполняются в одной транзакции. Сумма пересчитывается в валюту purchase.Currency
+// (пустая строка — базовая валюта) по текущему курсу. Если экземпляров недостаточно,
+// возвращается ErrOutOfStock, если промокод не подходит — *PromoCodeError, если курс валюты
+// не задан — ErrUnknownCurrency.
+func (m PurchaseModel) Buy(purchase *Purchase, code string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	return m.DB.QueryRowContext(ctx, query, purchase.UserID, purchase.BookID, purchase.Quantity, purchase.TotalPrice).Scan(&purchase.ID)
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Курс блокируется на чтение, чтобы он не изменился, пока покупка не записана
+	var rate *CurrencyRate
+	if purchase.Currency != "" && purchase.Currency != BaseCurrency {
+		rate, err = getCurrencyRate(ctx, tx, purchase.Currency, "FOR SHARE")
+		if err != nil {
+			return err
+		}
+	}
+
+	var price Money
+	err = tx.QueryRowContext(ctx, `
+    UPDATE editions
+    SET stock_quantity = stock_quantity - $1, updated_at = NOW(), version = version + 1
+    WHERE id = $2 AND stock_quantity >= $1
+    RETURNING book_id, price
+    `, purchase.Quantity, purchase.EditionID).Scan(&purchase.BookID, &price)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrOutOfStock
+		default:
+			return err
+		}
+	}
+
+	subtotal := price.Mul(purchase.Quantity)
+	purchase.Discount = NewMoney(0)
+	purchase.PromoCodeID = 0
+	if code != "" {
+		purchase.PromoCodeID, purchase.Discount, err = redeemPromoCode(ctx, tx, code, purchase, subtotal, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	purchase.TotalPrice = subtotal.Sub(purchase.Discount)
+	purchase.ChargedTotal, err = rate.Convert(purchase.TotalPrice)
+	if err != nil {
+		return err
+	}
+	purchase.Currency = purchase.ChargedTotal.Currency
+	purchase.ExchangeRate = "1"
+	if rate != nil {
+		purchase.ExchangeRate = rate.Rate
+	}
+
+	query := `
+    INSERT INTO purchases (user_id, book_id, edition_id, quantity, total_price, discount, promo_code_id,
+        currency, charged_total, exchange_rate, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), $8, $9, $10, NOW()) RETURNING id, created_at;
+    `
+	args := []interface{}{
+		purchase.UserID, purchase.BookID, purchase.EditionID, purchase.Quantity, purchase.TotalPrice,
+		purchase.Discount, purchase.PromoCodeID, purchase.Currency, purchase.ChargedTotal, purchase.ExchangeRate,
+	}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&purchase.ID, &purchase.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (m PurchaseModel) GetByUserID(userID int64) ([]*Book, error) {
 	query := `
-	SELECT b.id, b.title, b.author, b.price, b.stock_quantity, b.created_at, b.updated_at
+	SELECT ` + bookColumns + `
 	FROM purchases p
-	INNER JOIN books b ON p.book_id = b.id
+	INNER JOIN books ON p.book_id = books.id
 	WHERE p.user_id = $1
 	`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -51,15 +150,7 @@ func (m PurchaseModel) GetByUserID(userID int64) ([]*Book, error) {
 	var books []*Book
 	for rows.Next() {
 		var book Book
-		err := rows.Scan(
-			&book.ID,
-			&book.Title,
-			&book.Author,
-			&book.Price,
-			&book.StockQuantity,
-			&book.CreatedAt,
-			&book.UpdatedAt,
-		)
+		err := rows.Scan(book.scanDest()...)
 		if err != nil {
 			return nil, err
 		}
@@ -75,7 +166,7 @@ func (m PurchaseModel) GetByUserID(userID int64) ([]*Book, error) {
 
 func (m *PurchaseModel) GetAllForUser(userID int64) ([]*Purchase, error) {
 	query := `
-    SELECT id, user_id, book_id, quantity, total_price, created_at
+    SELECT ` + purchaseColumns + `
     FROM purchases
     WHERE user_id = $1;
     `
@@ -89,10 +180,11 @@ func (m *PurchaseModel) GetAllForUser(userID int64) ([]*Purchase, error) {
 
 	for rows.Next() {
 		var p Purchase
-		err := rows.Scan(&p.ID, &p.UserID, &p.BookID, &p.Quantity, &p.TotalPrice, &p.CreatedAt)
+		err := rows.Scan(p.scanDest()...)
 		if err != nil {
 			return nil, err
 		}
+		p.afterScan()
 		purchases = append(purchases, &p)
 	}
 
@@ -102,3 +194,71 @@ func (m *PurchaseModel) GetAllForUser(userID int64) ([]*Purchase, error) {
 
 	return purchases, nil
 }
+
+// ListForUser получает покупки пользователя постранично, по номеру страницы или по курсору.
+// GetAllForUser возвращает все покупки сразу и используется для выгрузки данных.
+func (m PurchaseModel) ListForUser(userID int64, filters Filters) ([]*Purchase, Metadata, error) {
+	columns := map[string]string{
+		"id":          "purchases.id",
+		"created_at":  "purchases.created_at",
+		"total_price": "purchases.total_price",
+	}
+
+	var args sqlArgs
+	conditions := "WHERE purchases.user_id = " + args.add(userID)
+	if keyset := filters.keyset(columns, &args); keyset != "" {
+		conditions += " AND " + keyset
+	}
+
+	total := "count(*) OVER()"
+	if filters.cursorMode() {
+		total = "0"
+	}
+
+	query := fmt.Sprintf(`
+    SELECT %s, %s
+    FROM purchases
+    %s
+    %s
+    %s
+    `, total, purchaseColumns, conditions, filters.orderBy(columns), filters.pageLimit(&args))
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	purchases := []*Purchase{}
+	for rows.Next() {
+		var p Purchase
+		err := rows.Scan(append([]interface{}{&totalRecords}, p.scanDest()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		p.afterScan()
+		purchases = append(purchases, &p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	purchases, metadata := keysetPage(filters, purchases, totalRecords)
+	return purchases, metadata, nil
+}
+
+// sortValue возвращает значение покупки для ключа сортировки, используется в курсорах
+func (p *Purchase) sortValue(column string) interface{} {
+	switch column {
+	case "created_at":
+		return p.CreatedAt
+	case "total_price":
+		return p.TotalPrice.String()
+	default:
+		return p.ID
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"time"
@@ -31,3 +32,37 @@ func (m RatingModel) Insert(rating *Rating) error {
 
 	return m.DB.QueryRow(query, args...).Scan(&rating.ID, &rating.CreatedAt)
 }
+
+// GetAllForUser возвращает все оценки, поставленные пользователем
+func (m RatingModel) GetAllForUser(userID int64) ([]*Rating, error) {
+	query := `
+        SELECT id, user_id, book_id, rating, created_at
+        FROM ratings
+        WHERE user_id = $1
+        ORDER BY created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ratings []*Rating
+	for rows.Next() {
+		var rating Rating
+		err := rows.Scan(&rating.ID, &rating.UserID, &rating.BookID, &rating.Rating, &rating.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, &rating)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ratings, nil
+}
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// headlineOptions настраивает фрагменты ts_headline: совпадения выделяются тегами <b>,
+// из длинного описания берется не больше двух фрагментов.
+const headlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// escapeHTML возвращает SQL-выражение, экранирующее спецсимволы HTML в тексте column, как
+// html.EscapeString. Фрагменты ts_headline строятся из экранированного текста, поэтому теги
+// <b> — единственная разметка в них, и их можно выводить как HTML.
+func escapeHTML(column string) string {
+	return `replace(replace(replace(replace(replace(` + column +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`
+}
+
+// BookHighlight содержит фрагменты названия и описания книги с выделенными совпадениями
+// поискового запроса. Текст фрагментов экранирован для HTML, совпадения обернуты в <b>.
+type BookHighlight struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+
+	// search — запрос, по которому построены фрагменты. По нему фрагменты строятся заново,
+	// когда название и описание книги заменяются переводом.
+	search string
+}
+
+// SearchConfig возвращает конфигурацию полнотекстового поиска Postgres для запроса:
+// "russian", если в нем есть кириллица, иначе "english". Вектор книги содержит основы слов
+// в обеих конфигурациях, поэтому от выбора зависит только разбор самого запроса.
+func SearchConfig(q string) string {
+	for _, r := range q {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return "russian"
+		}
+	}
+	return "english"
+}
+
+// loadHighlights заполняет поле Highlight у переданных книг. Фрагменты строятся отдельным
+// запросом только для книг текущей страницы, так как ts_headline работает медленно.
+func loadHighlights(ctx context.Context, db *sql.DB, search string, books ...*Book) error {
+	if search == "" || len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(books))
+	byID := make(map[int64]*Book, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+		byID[book.ID] = book
+	}
+
+	// Совпадение в описании проверяется по самому описанию: search_vector содержит и
+	// переводы, и книга могла найтись по переведенному тексту.
+	query := `
+    SELECT id,
+           ts_headline($2::regconfig, ` + escapeHTML("title") + `, websearch_to_tsquery($2::regconfig, $3), $4),
+           CASE WHEN to_tsvector($2::regconfig, description) @@ websearch_to_tsquery($2::regconfig, $3)
+                THEN ts_headline($2::regconfig, ` + escapeHTML("description") + `, websearch_to_tsquery($2::regconfig, $3), $4)
+                ELSE '' END
+    FROM books
+    WHERE id = ANY($1)
+    `
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids), SearchConfig(search), search, headlineOptions)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id        int64
+			highlight BookHighlight
+		)
+		err := rows.Scan(&id, &highlight.Title, &highlight.Description)
+		if err != nil {
+			return err
+		}
+		if book := byID[id]; book != nil {
+			highlight.search = search
+			book.Highlight = &highlight
+		}
+	}
+	return rows.Err()
+}
+
+// localizeHighlights строит фрагменты книг заново по их переводам на язык locale, чтобы
+// фрагменты совпадали с переведенными названием и описанием. Фрагменты книг без перевода
+// не меняются, как и фрагмент описания, если перевод описания пустой.
+func localizeHighlights(ctx context.Context, db *sql.DB, locale string, books ...*Book) error {
+	bySearch := make(map[string]map[int64][]*Book)
+	for _, book := range books {
+		if book.Highlight == nil || book.Highlight.search == "" {
+			continue
+		}
+		search := book.Highlight.search
+		if bySearch[search] == nil {
+			bySearch[search] = make(map[int64][]*Book)
+		}
+		bySearch[search][book.ID] = append(bySearch[search][book.ID], book)
+	}
+
+	for search, byID := range bySearch {
+		if err := localizeHighlightsFor(ctx, db, locale, search, byID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localizeHighlightsFor строит фрагменты по переводам для книг, найденных по запросу search
+func localizeHighlightsFor(ctx context.Context, db *sql.DB, locale, search string, byID map[int64][]*Book) error {
+	ids := make([]int64, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+
+	query := `
+    SELECT book_id,
+           ts_headline($2::regconfig, ` + escapeHTML("title") + `, websearch_to_tsquery($2::regconfig, $3), $4),
+           CASE WHEN description = '' THEN NULL
+                WHEN to_tsvector($2::regconfig, description) @@ websearch_to_tsquery($2::regconfig, $3)
+                THEN ts_headline($2::regconfig, ` + escapeHTML("description") + `, websearch_to_tsquery($2::regconfig, $3), $4)
+                ELSE '' END
+    FROM book_translations
+    WHERE book_id = ANY($1) AND locale = $5
+    `
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids), SearchConfig(search), search, headlineOptions, locale)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id          int64
+			title       string
+			description sql.NullString
+		)
+		if err := rows.Scan(&id, &title, &description); err != nil {
+			return err
+		}
+		for _, book := range byID[id] {
+			book.Highlight.Title = title
+			if description.Valid {
+				book.Highlight.Description = description.String
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// Suggestion — вариант автодополнения: название книги или имя автора
+type Suggestion struct {
+	Type  string  `json:"type"`
+	ID    int64   `json:"id"`
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+// Suggest возвращает до limit названий книг и имен авторов, похожих на введенный текст.
+// Используется word_similarity из pg_trgm, поэтому находятся и начала слов ("Gats"), и слова
+// с опечатками ("Gatsbi").
+func (m BookModel) Suggest(q string, limit int) ([]*Suggestion, error) {
+	query := `
+    SELECT type, id, text, score
+    FROM (
+        SELECT 'book' AS type, id::bigint AS id, title AS text, word_similarity($1, title) AS score
+        FROM books
+        WHERE $1 <% title
+        UNION ALL
+        SELECT 'author', id, name, word_similarity($1, name)
+        FROM authors
+        WHERE $1 <% name
+    ) AS suggestions
+    ORDER BY score DESC, text ASC
+    LIMIT $2
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*Suggestion{}
+	for rows.Next() {
+		var suggestion Suggestion
+		err := rows.Scan(&suggestion.Type, &suggestion.ID, &suggestion.Text, &suggestion.Score)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+	return suggestions, rows.Err()
+}
+
+// DidYouMean возвращает название книги или имя автора, наиболее похожее на запрос, или
+// пустую строку, если ничего достаточно похожего нет.
+func (m BookModel) DidYouMean(q string) (string, error) {
+	query := `
+    SELECT text
+    FROM (
+        SELECT title AS text, word_similarity($1, title) AS score FROM books WHERE $1 <% title
+        UNION ALL
+        SELECT name, word_similarity($1, name) FROM authors WHERE $1 <% name
+    ) AS candidates
+    WHERE LOWER(text) <> LOWER($1)
+    ORDER BY score DESC, text ASC
+    LIMIT 1
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var text string
+	err := m.DB.QueryRowContext(ctx, query, q).Scan(&text)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return text, err
+}
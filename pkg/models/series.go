@@ -0,0 +1,395 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrDuplicateSeries возвращается при попытке сохранить серию с уже существующим названием
+	ErrDuplicateSeries = errors.New("duplicate series")
+
+	// ErrDuplicatePosition возвращается, если в серии уже есть книга на этой позиции
+	ErrDuplicatePosition = errors.New("duplicate series position")
+)
+
+// Series представляет книжную серию или цикл
+type Series struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Version     int32     `json:"version"`
+}
+
+// SeriesVolume — книга серии на своей позиции. Available показывает, есть ли на складе
+// хотя бы одно издание книги.
+type SeriesVolume struct {
+	Position      float64 `json:"position"`
+	BookID        int64   `json:"book_id"`
+	Title         string  `json:"title"`
+	Author        string  `json:"author"`
+	Price         Money   `json:"price"`
+	StockQuantity int     `json:"stock_quantity"`
+	Available     bool    `json:"available"`
+}
+
+// BookSeries описывает место книги в серии, например "книга 3 из 9 серии The Expanse".
+// Total — количество книг в серии.
+type BookSeries struct {
+	ID       int64   `json:"id"`
+	Name     string  `json:"name"`
+	Position float64 `json:"position"`
+	Total    int     `json:"total"`
+}
+
+// NextVolume — следующая книга серии после данной
+type NextVolume struct {
+	Series *BookSeries   `json:"series"`
+	Volume *SeriesVolume `json:"volume"`
+}
+
+// SeriesModel обрабатывает операции с сериями в базе данных
+type SeriesModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// seriesSortColumns сопоставляет значения параметра sort списка серий выражениям SQL
+var seriesSortColumns = map[string]string{
+	"id":   "id",
+	"name": "LOWER(name)",
+}
+
+// volumeColumns перечисляет столбцы книги серии в порядке, в котором их считывает scanDest
+const volumeColumns = `book_series.position, books.id, books.title, books.author, ` + bookPrice + `, ` + bookStock
+
+// scanDest возвращает указатели на поля книги серии в порядке столбцов volumeColumns
+func (v *SeriesVolume) scanDest() []interface{} {
+	return []interface{}{&v.Position, &v.BookID, &v.Title, &v.Author, &v.Price, &v.StockQuantity}
+}
+
+// Insert добавляет новую серию
+func (m SeriesModel) Insert(series *Series) error {
+	query := `
+        INSERT INTO series (name, description)
+        VALUES ($1, $2)
+        RETURNING id, created_at, updated_at, version
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, series.Name, series.Description).Scan(
+		&series.ID, &series.CreatedAt, &series.UpdatedAt, &series.Version,
+	)
+	if err != nil {
+		if isDuplicateSeries(err) {
+			return ErrDuplicateSeries
+		}
+		return err
+	}
+	return nil
+}
+
+// Get возвращает серию по ID
+func (m SeriesModel) Get(id int64) (*Series, error) {
+	query := `
+    SELECT id, name, description, created_at, updated_at, version
+    FROM series
+    WHERE id = $1
+    `
+	var series Series
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&series.ID, &series.Name, &series.Description, &series.CreatedAt, &series.UpdatedAt, &series.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &series, nil
+}
+
+// GetAll возвращает серии, название которых содержит name, с учетом пагинации и сортировки
+func (m SeriesModel) GetAll(name string, filters Filters) ([]*Series, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, name, description, created_at, updated_at, version
+        FROM series
+        WHERE (LOWER(name) LIKE LOWER($1) OR $1 = '')
+        %s
+        LIMIT $2 OFFSET $3
+        `, filters.orderBy(seriesSortColumns))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, "%"+name+"%", filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	list := []*Series{}
+	for rows.Next() {
+		var series Series
+		err := rows.Scan(
+			&totalRecords, &series.ID, &series.Name, &series.Description,
+			&series.CreatedAt, &series.UpdatedAt, &series.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		list = append(list, &series)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return list, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Update сохраняет изменения серии. Если запись была изменена или удалена с момента
+// чтения, возвращается ErrEditConflict.
+func (m SeriesModel) Update(series *Series) error {
+	query := `
+    UPDATE series
+    SET name = $1, description = $2, updated_at = NOW(), version = version + 1
+    WHERE id = $3 AND version = $4
+    RETURNING updated_at, version
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{series.Name, series.Description, series.ID, series.Version}
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&series.UpdatedAt, &series.Version)
+	if err != nil {
+		switch {
+		case isDuplicateSeries(err):
+			return ErrDuplicateSeries
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete удаляет серию. Книги остаются в каталоге, удаляется только их привязка к серии.
+func (m SeriesModel) Delete(id int64) error {
+	query := `
+    DELETE FROM series
+    WHERE id = $1
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Volumes возвращает книги серии по порядку позиций
+func (m SeriesModel) Volumes(seriesID int64) ([]*SeriesVolume, error) {
+	query := `
+    SELECT ` + volumeColumns + `
+    FROM book_series
+    INNER JOIN books ON books.id = book_series.book_id
+    WHERE book_series.series_id = $1
+    ORDER BY book_series.position
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	volumes := []*SeriesVolume{}
+	for rows.Next() {
+		var volume SeriesVolume
+		if err := rows.Scan(volume.scanDest()...); err != nil {
+			return nil, err
+		}
+		volume.Available = volume.StockQuantity > 0
+		volumes = append(volumes, &volume)
+	}
+	return volumes, rows.Err()
+}
+
+// SetVolume включает книгу в серию на позицию position или переносит ее на эту позицию,
+// если книга уже в серии. Занятая другой книгой позиция возвращает ErrDuplicatePosition.
+func (m SeriesModel) SetVolume(seriesID, bookID int64, position float64) error {
+	query := `
+    INSERT INTO book_series (book_id, series_id, position)
+    VALUES ($1, $2, $3)
+    ON CONFLICT (book_id, series_id) DO UPDATE SET position = EXCLUDED.position
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, bookID, seriesID, position)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `violates unique constraint "book_series_position_key"`):
+			return ErrDuplicatePosition
+		case strings.Contains(err.Error(), `violates foreign key constraint "book_series_book_id_fkey"`):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveVolume исключает книгу из серии
+func (m SeriesModel) RemoveVolume(seriesID, bookID int64) error {
+	query := `
+    DELETE FROM book_series
+    WHERE series_id = $1 AND book_id = $2
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, seriesID, bookID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Next возвращает для каждой серии, в которую входит книга, следующую за ней книгу серии.
+// Серии, где книга последняя, в результат не попадают.
+func (m SeriesModel) Next(bookID int64) ([]*NextVolume, error) {
+	query := `
+    SELECT DISTINCT ON (series.id) series.id, series.name, current.position,
+        (SELECT COUNT(*) FROM book_series total WHERE total.series_id = series.id), ` + volumeColumns + `
+    FROM book_series current
+    INNER JOIN series ON series.id = current.series_id
+    INNER JOIN book_series ON book_series.series_id = current.series_id AND book_series.position > current.position
+    INNER JOIN books ON books.id = book_series.book_id
+    WHERE current.book_id = $1
+    ORDER BY series.id, book_series.position
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	next := []*NextVolume{}
+	for rows.Next() {
+		var (
+			series BookSeries
+			volume SeriesVolume
+		)
+		dest := []interface{}{&series.ID, &series.Name, &series.Position, &series.Total}
+		if err := rows.Scan(append(dest, volume.scanDest()...)...); err != nil {
+			return nil, err
+		}
+		volume.Available = volume.StockQuantity > 0
+		next = append(next, &NextVolume{Series: &series, Volume: &volume})
+	}
+	return next, rows.Err()
+}
+
+// loadBookSeries заполняет поле Series у переданных книг одним запросом
+func loadBookSeries(ctx context.Context, db *sql.DB, books ...*Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(books))
+	byID := make(map[int64]*Book, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+		byID[book.ID] = book
+	}
+
+	query := `
+    SELECT book_series.book_id, series.id, series.name, book_series.position,
+        (SELECT COUNT(*) FROM book_series total WHERE total.series_id = series.id)
+    FROM book_series
+    INNER JOIN series ON series.id = book_series.series_id
+    WHERE book_series.book_id = ANY($1)
+    ORDER BY book_series.book_id, series.name
+    `
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			bookID int64
+			series BookSeries
+		)
+		err := rows.Scan(&bookID, &series.ID, &series.Name, &series.Position, &series.Total)
+		if err != nil {
+			return err
+		}
+		if book := byID[bookID]; book != nil {
+			book.Series = append(book.Series, &series)
+		}
+	}
+	return rows.Err()
+}
+
+// ValidateSeries проверяет поля серии
+func ValidateSeries(v *validator.Validator, series *Series) {
+	v.Check(strings.TrimSpace(series.Name) != "", "name", "must be provided")
+	v.Check(len(series.Name) <= 255, "name", "must not be more than 255 bytes long")
+	v.Check(len(series.Description) <= 10_000, "description", "must not be more than 10000 bytes long")
+}
+
+// ValidatePosition проверяет позицию книги в серии. Позиция хранится с точностью до сотых.
+func ValidatePosition(v *validator.Validator, position float64) {
+	v.Check(position > 0, "position", "must be greater than zero")
+	v.Check(position < 10_000, "position", "must be less than 10000")
+	v.Check(math.Abs(position*100-math.Round(position*100)) < 1e-6, "position", "must have at most two decimal places")
+}
+
+// isDuplicateSeries сообщает, нарушает ли ошибка уникальность названия серии
+func isDuplicateSeries(err error) bool {
+	return strings.Contains(err.Error(), `violates unique constraint "series_name_key"`)
+}
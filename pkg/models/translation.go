@@ -0,0 +1,402 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zhan1bek/BookStore/pkg/i18n"
+	"github.com/Zhan1bek/BookStore/pkg/validator"
+	"github.com/lib/pq"
+)
+
+// BookTranslation — название и описание книги на языке Locale. В самой книге тексты
+// хранятся на языке по умолчанию, перевод заменяет их для читателей на другом языке.
+type BookTranslation struct {
+	BookID      int64     `json:"book_id"`
+	Locale      string    `json:"locale"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Version     int32     `json:"version"`
+}
+
+// CategoryTranslation — название категории на языке Locale
+type CategoryTranslation struct {
+	CategoryID int64     `json:"category_id"`
+	Locale     string    `json:"locale"`
+	Name       string    `json:"name"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Version    int32     `json:"version"`
+}
+
+// TranslationModel обрабатывает операции с переводами книг и категорий в базе данных
+type TranslationModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// BookTranslations возвращает все переводы книги, упорядоченные по языку
+func (m TranslationModel) BookTranslations(bookID int64) ([]*BookTranslation, error) {
+	query := `
+    SELECT book_id, locale, title, description, updated_at, version
+    FROM book_translations
+    WHERE book_id = $1
+    ORDER BY locale
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []*BookTranslation{}
+	for rows.Next() {
+		var t BookTranslation
+		err := rows.Scan(&t.BookID, &t.Locale, &t.Title, &t.Description, &t.UpdatedAt, &t.Version)
+		if err != nil {
+			return nil, err
+		}
+		translations = append(translations, &t)
+	}
+	return translations, rows.Err()
+}
+
+// SetBookTranslation добавляет перевод книги или заменяет существующий перевод на тот же язык
+func (m TranslationModel) SetBookTranslation(t *BookTranslation) error {
+	query := `
+    INSERT INTO book_translations (book_id, locale, title, description)
+    VALUES ($1, $2, $3, $4)
+    ON CONFLICT (book_id, locale) DO UPDATE
+    SET title = EXCLUDED.title, description = EXCLUDED.description,
+        updated_at = NOW(), version = book_translations.version + 1
+    RETURNING updated_at, version
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, t.BookID, t.Locale, t.Title, t.Description).Scan(&t.UpdatedAt, &t.Version)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "book_translations_book_id_fkey"):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBookTranslation удаляет перевод книги на язык locale
+func (m TranslationModel) DeleteBookTranslation(bookID int64, locale string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM book_translations WHERE book_id = $1 AND locale = $2`, bookID, locale)
+	return deleted(result, err)
+}
+
+// CategoryTranslations возвращает все переводы категории, упорядоченные по языку
+func (m TranslationModel) CategoryTranslations(categoryID int64) ([]*CategoryTranslation, error) {
+	query := `
+    SELECT category_id, locale, name, updated_at, version
+    FROM category_translations
+    WHERE category_id = $1
+    ORDER BY locale
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []*CategoryTranslation{}
+	for rows.Next() {
+		var t CategoryTranslation
+		err := rows.Scan(&t.CategoryID, &t.Locale, &t.Name, &t.UpdatedAt, &t.Version)
+		if err != nil {
+			return nil, err
+		}
+		translations = append(translations, &t)
+	}
+	return translations, rows.Err()
+}
+
+// SetCategoryTranslation добавляет перевод категории или заменяет существующий перевод на тот
+// же язык
+func (m TranslationModel) SetCategoryTranslation(t *CategoryTranslation) error {
+	query := `
+    INSERT INTO category_translations (category_id, locale, name)
+    VALUES ($1, $2, $3)
+    ON CONFLICT (category_id, locale) DO UPDATE
+    SET name = EXCLUDED.name, updated_at = NOW(), version = category_translations.version + 1
+    RETURNING updated_at, version
+    `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, t.CategoryID, t.Locale, t.Name).Scan(&t.UpdatedAt, &t.Version)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "category_translations_category_id_fkey"):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteCategoryTranslation удаляет перевод категории на язык locale
+func (m TranslationModel) DeleteCategoryTranslation(categoryID int64, locale string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM category_translations WHERE category_id = $1 AND locale = $2`, categoryID, locale)
+	return deleted(result, err)
+}
+
+// deleted возвращает ErrRecordNotFound, если DELETE не затронул ни одной строки
+func deleted(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// LocalizeBooks заменяет названия и описания книг и названия их категорий переводами на язык
+// locale. Фрагменты найденных поиском книг строятся заново по переводу. Тексты без перевода
+// остаются на языке по умолчанию. Пустой locale означает язык по умолчанию, и книги не
+// меняются.
+func (m TranslationModel) LocalizeBooks(locale string, books ...*Book) error {
+	if locale == "" || len(books) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	ids := make([]int64, len(books))
+	byID := make(map[int64][]*Book, len(books))
+	var categories []*BookCategory
+	for i, book := range books {
+		ids[i] = book.ID
+		byID[book.ID] = append(byID[book.ID], book)
+		categories = append(categories, book.Categories...)
+	}
+
+	query := `
+    SELECT book_id, title, description
+    FROM book_translations
+    WHERE book_id = ANY($1) AND locale = $2
+    `
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), locale)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			bookID             int64
+			title, description string
+		)
+		if err := rows.Scan(&bookID, &title, &description); err != nil {
+			return err
+		}
+		for _, book := range byID[bookID] {
+			book.Title = title
+			if description != "" {
+				book.Description = description
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if err = localizeHighlights(ctx, m.DB, locale, books...); err != nil {
+		return err
+	}
+
+	return localizeCategoryNames(ctx, m.DB, locale, categories, func(c *BookCategory) (int64, *string) {
+		return c.ID, &c.Name
+	})
+}
+
+// LocalizeVolumes заменяет названия книг серии переводами на язык locale
+func (m TranslationModel) LocalizeVolumes(locale string, volumes ...*SeriesVolume) error {
+	if locale == "" || len(volumes) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	ids := make([]int64, len(volumes))
+	for i, volume := range volumes {
+		ids[i] = volume.BookID
+	}
+
+	query := `
+    SELECT book_id, title
+    FROM book_translations
+    WHERE book_id = ANY($1) AND locale = $2
+    `
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), locale)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	titles := make(map[int64]string)
+	for rows.Next() {
+		var (
+			bookID int64
+			title  string
+		)
+		if err := rows.Scan(&bookID, &title); err != nil {
+			return err
+		}
+		titles[bookID] = title
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, volume := range volumes {
+		if title, ok := titles[volume.BookID]; ok {
+			volume.Title = title
+		}
+	}
+	return nil
+}
+
+// LocalizeCategories заменяет названия категорий переводами на язык locale
+func (m TranslationModel) LocalizeCategories(locale string, categories ...*Category) error {
+	if locale == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return localizeCategoryNames(ctx, m.DB, locale, categories, func(c *Category) (int64, *string) {
+		return c.ID, &c.Name
+	})
+}
+
+// LocalizeCategoryTree заменяет названия категорий дерева переводами на язык locale
+func (m TranslationModel) LocalizeCategoryTree(locale string, tree []*CategoryNode) error {
+	var categories []*Category
+	var walk func(nodes []*CategoryNode)
+	walk = func(nodes []*CategoryNode) {
+		for _, node := range nodes {
+			categories = append(categories, node.Category)
+			walk(node.Children)
+		}
+	}
+	walk(tree)
+
+	return m.LocalizeCategories(locale, categories...)
+}
+
+// LocalizeCategoryFacet заменяет подписи значений фасета категорий переводами на язык locale
+func (m TranslationModel) LocalizeCategoryFacet(locale string, values []*FacetValue) error {
+	if locale == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return localizeCategoryNames(ctx, m.DB, locale, values, func(v *FacetValue) (int64, *string) {
+		id, _ := v.Value.(int64)
+		return id, &v.Label
+	})
+}
+
+// localizeCategoryNames заменяет названия категорий переводами одним запросом. field
+// возвращает ID категории элемента и указатель на поле с ее названием.
+func localizeCategoryNames[T any](ctx context.Context, db *sql.DB, locale string, items []T, field func(T) (int64, *string)) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i], _ = field(item)
+	}
+
+	query := `
+    SELECT category_id, name
+    FROM category_translations
+    WHERE category_id = ANY($1) AND locale = $2
+    `
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids), locale)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names := make(map[int64]string)
+	for rows.Next() {
+		var (
+			id   int64
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		names[id] = name
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		id, name := field(item)
+		if translated, ok := names[id]; ok {
+			*name = translated
+		}
+	}
+	return nil
+}
+
+// ValidateTranslationLocale проверяет язык перевода. Перевод на язык по умолчанию не нужен:
+// тексты на нем хранятся в самой книге или категории.
+func ValidateTranslationLocale(v *validator.Validator, locale, defaultLocale string) {
+	v.Check(validator.In(locale, i18n.Locales...), "locale", "must be one of "+strings.Join(i18n.Locales, ", "))
+	v.Check(locale != defaultLocale, "locale", "a translation for this locale is not needed, the default locale is stored on the record itself")
+}
+
+// ValidateBookTranslation проверяет перевод книги
+func ValidateBookTranslation(v *validator.Validator, t *BookTranslation, defaultLocale string) {
+	ValidateTranslationLocale(v, t.Locale, defaultLocale)
+	v.Check(strings.TrimSpace(t.Title) != "", "title", "must be provided")
+	v.Check(len(t.Title) <= 255, "title", "must not be more than 255 bytes long")
+	v.Check(len(t.Description) <= 10000, "description", "must not be more than 10000 bytes long")
+}
+
+// ValidateCategoryTranslation проверяет перевод категории
+func ValidateCategoryTranslation(v *validator.Validator, t *CategoryTranslation, defaultLocale string) {
+	ValidateTranslationLocale(v, t.Locale, defaultLocale)
+	v.Check(strings.TrimSpace(t.Name) != "", "name", "must be provided")
+	v.Check(len(t.Name) <= 100, "name", "must not be more than 100 bytes long")
+}
@@ -6,6 +6,8 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Zhan1bek/BookStore/pkg/validator"
@@ -25,6 +27,7 @@ type User struct {
 	Email     string   `json:"email"`
 	Password  password `json:"-"`
 	Activated bool     `json:"activated"`
+	Currency  string   `json:"currency,omitempty"` // Preferred currency for prices, empty for the base currency
 	Version   int      `json:"-"`
 	CreatedAt string   `json:"createdAt"`
 	UpdatedAt string   `json:"updatedAt"`
@@ -82,7 +85,7 @@ func (m UserModel) GetByToken(tokenScope, tokenPlaintext string) (*User, error)
 	query := `
 		SELECT 
 			users.id, users.created_at, users.name, users.email, 
-			users.password_hash, users.activated, users.version
+			users.password_hash, users.activated, COALESCE(users.currency, ''), users.version
 		FROM       users
         INNER JOIN tokens
 			ON users.id = tokens.user_id
@@ -113,6 +116,7 @@ func (m UserModel) GetByToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Currency,
 		&user.Version,
 	)
 	if err != nil {
@@ -133,7 +137,7 @@ func (m UserModel) GetByToken(tokenScope, tokenPlaintext string) (*User, error)
 // return one record (or none at all, in which case we return a ErrRecordNotFound error).
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-	SELECT id, created_at, name, email, password_hash, activated, version
+	SELECT id, created_at, name, email, password_hash, activated, COALESCE(currency, ''), version
 	FROM users
 	WHERE email = $1`
 	var user User
@@ -146,6 +150,37 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Currency,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+// Retrieve the User details from the database based on the user's ID.
+func (m UserModel) Get(id int64) (*User, error) {
+	query := `
+	SELECT id, created_at, name, email, password_hash, activated, COALESCE(currency, ''), version
+	FROM users
+	WHERE id = $1`
+	var user User
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Currency,
 		&user.Version,
 	)
 	if err != nil {
@@ -167,14 +202,15 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 func (m UserModel) Update(user *User) error {
 	query := `
 	UPDATE users
-	SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
-	WHERE id = $5 AND version = $6
+	SET name = $1, email = $2, password_hash = $3, activated = $4, currency = NULLIF($5, ''), version = version + 1
+	WHERE id = $6 AND version = $7
 	RETURNING version`
 	args := []interface{}{
 		user.Name,
 		user.Email,
 		user.Password.hash,
 		user.Activated,
+		user.Currency,
 		user.ID,
 		user.Version,
 	}
@@ -185,6 +221,8 @@ func (m UserModel) Update(user *User) error {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
 			return ErrDuplicateEmail
+		case strings.Contains(err.Error(), `violates foreign key constraint "users_currency_fkey"`):
+			return ErrUnknownCurrency
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
 		default:
@@ -194,10 +232,77 @@ func (m UserModel) Update(user *User) error {
 	return nil
 }
 
-// The Set() method calculates the bcrypt hash of a plaintext password, and stores both
-// the hash and the plaintext versions in the struct.
+// Delete removes a user account. Comments and ratings are kept but detached from the user, so
+// that book ratings stay intact, and purchases are kept for accounting without any link to
+// the person who made them. Tokens, permissions and failed login records are deleted. All of
+// this happens in a single transaction.
+func (m UserModel) Delete(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction has been committed.
+	defer tx.Rollback()
+
+	queries := []string{
+		`UPDATE comments SET user_id = NULL WHERE user_id = $1`,
+		`UPDATE ratings SET user_id = NULL WHERE user_id = $1`,
+		`UPDATE purchases SET user_id = NULL WHERE user_id = $1`,
+		`DELETE FROM login_attempts WHERE email = (SELECT email FROM users WHERE id = $1)`,
+		`DELETE FROM tokens WHERE user_id = $1`,
+		`DELETE FROM users_permissions WHERE user_id = $1`,
+	}
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+// DeleteUnactivated deletes the accounts that were never activated and were created before the
+// provided time, returning the number of deleted accounts. Accounts that already have purchases,
+// comments or ratings are left alone. Their tokens and permissions are removed by the ON DELETE
+// CASCADE constraints.
+func (m UserModel) DeleteUnactivated(createdBefore time.Time) (int64, error) {
+	query := `
+	DELETE FROM users
+	WHERE activated = false
+		AND created_at < $1
+		AND NOT EXISTS (SELECT 1 FROM purchases WHERE purchases.user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM comments WHERE comments.user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM ratings WHERE ratings.user_id = users.id)`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// The Set() method calculates the Argon2id hash of a plaintext password using the current
+// PasswordParams, and stores both the hash and the plaintext versions in the struct.
 func (p *password) Set(plaintextPassword string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	hash, err := hashArgon2id(plaintextPassword, PasswordParams)
 	if err != nil {
 		return err
 	}
@@ -208,8 +313,16 @@ func (p *password) Set(plaintextPassword string) error {
 
 // The Matches() method checks whether the provided plaintext password matches the
 // hashed password stored in the struct, returning true if it matches and false
-// otherwise.
+// otherwise. Both Argon2id hashes and legacy bcrypt hashes are supported.
 func (p *password) Matches(plaintextPassword string) (bool, error) {
+	if isArgon2id(p.hash) {
+		return matchesArgon2id(plaintextPassword, p.hash)
+	}
+	// bcrypt silently ignores everything after the first 72 bytes, and legacy passwords
+	// were never longer than that, so a longer password can't be the right one.
+	if len(plaintextPassword) > 72 {
+		return false, nil
+	}
 	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintextPassword))
 	if err != nil {
 		switch {
@@ -222,6 +335,36 @@ func (p *password) Matches(plaintextPassword string) (bool, error) {
 	return true, nil
 }
 
+// The NeedsRehash() method reports whether the stored hash is a legacy bcrypt hash or an
+// Argon2id hash created with parameters other than the current PasswordParams.
+func (p *password) NeedsRehash() bool {
+	if !isArgon2id(p.hash) {
+		return true
+	}
+	params, _, _, err := decodeArgon2id(p.hash)
+	if err != nil {
+		return true
+	}
+	return params != PasswordParams
+}
+
+// dummyPassword is checked instead of a real password when a login names an email address
+// without an account. It is hashed on first use, with the same settings as real passwords.
+var dummyPassword struct {
+	once     sync.Once
+	password password
+}
+
+// MatchesDummyPassword takes as long as Matches does for an existing account. Calling it for
+// unknown email addresses keeps the response time from revealing which addresses are registered.
+func MatchesDummyPassword(plaintextPassword string) {
+	dummyPassword.once.Do(func() {
+		// Nobody logs in with this password, so its value doesn't matter.
+		_ = dummyPassword.password.Set("dummy password for unknown accounts")
+	})
+	_, _ = dummyPassword.password.Matches(plaintextPassword)
+}
+
 func ValidateEmail(v *validator.Validator, email string) {
 	v.Check(email != "", "email", "must be provided")
 	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
@@ -230,7 +373,7 @@ func ValidateEmail(v *validator.Validator, email string) {
 func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 	v.Check(password != "", "password", "must be provided")
 	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	v.Check(len(password) <= 1024, "password", "must not be more than 1024 bytes long")
 }
 
 func ValidateUser(v *validator.Validator, user *User) {
@@ -242,6 +385,8 @@ func ValidateUser(v *validator.Validator, user *User) {
 	// ValidatePasswordPlaintext() helper.
 	if user.Password.plaintext != nil {
 		ValidatePasswordPlaintext(v, *user.Password.plaintext)
+		// Reject breached, personal and easily guessed passwords as well.
+		validator.CheckPassword(v, "password", *user.Password.plaintext, user.Name, user.Email)
 	}
 	// If the password hash is ever nil, this will be due to a logic error in our
 	// codebase (probably because we forgot to set a password for the user). It's a
@@ -260,7 +405,8 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 	// Set up the SQL query.
 	query := `
-SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated,
+COALESCE(users.currency, ''), users.version
 FROM users
 INNER JOIN tokens
 ON users.id = tokens.user_id
@@ -284,6 +430,7 @@ AND tokens.expiry > $3`
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Currency,
 		&user.Version,
 	)
 	if err != nil {
@@ -297,3 +444,59 @@ AND tokens.expiry > $3`
 	// Return the matching user.
 	return &user, nil
 }
+
+// GetForAuthenticationToken returns the user of a plaintext authentication token together with
+// the impersonation details if the token was issued to a staff user acting as them, or nil for
+// an ordinary token. Both are read with a single query, as this runs on every authenticated
+// request.
+func (m UserModel) GetForAuthenticationToken(tokenPlaintext string) (*User, *Impersonation, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+	query := `
+SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated,
+COALESCE(users.currency, ''), users.version, impersonations.staff_user_id, COALESCE(impersonations.read_only, false)
+FROM users
+INNER JOIN tokens
+ON users.id = tokens.user_id
+LEFT JOIN impersonations
+ON impersonations.token_hash = tokens.hash
+WHERE tokens.hash = $1
+AND tokens.scope = $2
+AND tokens.expiry > $3`
+	args := []interface{}{tokenHash[:], ScopeAuthentication, time.Now()}
+	var (
+		user        User
+		staffUserID sql.NullInt64
+		readOnly    bool
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Currency,
+		&user.Version,
+		&staffUserID,
+		&readOnly,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+	if !staffUserID.Valid {
+		return &user, nil, nil
+	}
+	impersonation := &Impersonation{
+		StaffUserID:  staffUserID.Int64,
+		TargetUserID: user.ID,
+		ReadOnly:     readOnly,
+	}
+	return &user, impersonation, nil
+}
@@ -0,0 +1,211 @@
+package validator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// breachedData holds the bundled list of breached password hashes. See the header of the file
+// for its format.
+//
+//go:embed data/breached_sha1.txt
+var breachedData []byte
+
+var (
+	breachedOnce   sync.Once
+	breachedRanges map[string]map[string]bool
+)
+
+// keyboardRows are checked for runs of adjacent keys such as "qwerty" or "asdfgh".
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"йцукенгшщзхъ",
+	"фывапролджэ",
+	"ячсмитьбю",
+}
+
+// minPasswordEntropy is the minimum estimated entropy, in bits, of an acceptable password.
+const minPasswordEntropy = 30
+
+// loadBreached parses the bundled list into a map from hash prefix to the set of suffixes.
+func loadBreached() {
+	breachedRanges = make(map[string]map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(breachedData))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		prefix, suffix, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		if breachedRanges[prefix] == nil {
+			breachedRanges[prefix] = make(map[string]bool)
+		}
+		breachedRanges[prefix][suffix] = true
+	}
+}
+
+// Breached returns true if the password is in the bundled list of breached passwords. Only the
+// suffixes sharing the password hash's five character prefix are searched, the same way the
+// k-anonymity range lookups work.
+func Breached(password string) bool {
+	breachedOnce.Do(loadBreached)
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	return breachedRanges[hash[:5]][hash[5:]]
+}
+
+// ContainsPersonalInfo returns true if the password contains the user's name, any part of it
+// at least three characters long, the email address or its local part.
+func ContainsPersonalInfo(password, name, email string) bool {
+	password = strings.ToLower(password)
+
+	var parts []string
+	name = strings.ToLower(name)
+	parts = append(parts, strings.Join(strings.Fields(name), ""))
+	parts = append(parts, strings.Fields(name)...)
+
+	email = strings.ToLower(email)
+	if local, _, found := strings.Cut(email, "@"); found {
+		parts = append(parts, email, local)
+	}
+
+	for _, part := range parts {
+		if len([]rune(part)) >= 3 && strings.Contains(password, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SimplePattern returns true if the password is a single repeated block (such as "aaaaaaaa" or
+// "abcabcabc"), a run of consecutive characters (such as "12345678" or "abcdefgh") or a run of
+// adjacent keyboard keys (such as "qwertyui").
+func SimplePattern(password string) bool {
+	runes := []rune(strings.ToLower(password))
+	n := len(runes)
+	if n == 0 {
+		return false
+	}
+
+	// Repeated block.
+	for size := 1; size <= n/2; size++ {
+		if n%size == 0 && strings.Repeat(string(runes[:size]), n/size) == string(runes) {
+			return true
+		}
+	}
+
+	// Consecutive characters, ascending or descending.
+	if n > 1 {
+		step := runes[1] - runes[0]
+		if step == 1 || step == -1 {
+			sequence := true
+			for i := 2; i < n; i++ {
+				if runes[i]-runes[i-1] != step {
+					sequence = false
+					break
+				}
+			}
+			if sequence {
+				return true
+			}
+		}
+	}
+
+	// Adjacent keyboard keys, in either direction.
+	s := string(runes)
+	for _, row := range keyboardRows {
+		if strings.Contains(row, s) || strings.Contains(reverse(row), s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PasswordEntropy estimates the entropy of the password in bits from the size of the
+// character classes used. Characters repeating or continuing a sequence from the previous
+// character don't add to the estimate.
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	var prev rune
+	effective := 0
+
+	for i, r := range password {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			lower = true
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+
+		if i == 0 || (r != prev && r != prev+1 && r != prev-1) {
+			effective++
+		}
+		prev = r
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 66
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	return float64(effective) * math.Log2(float64(pool))
+}
+
+// CheckPassword adds an error message for key if the password is breached, contains the
+// user's name or email address, or is too easy to guess. Only the first failing check is
+// reported.
+func CheckPassword(v *Validator, key, password, name, email string) {
+	v.Check(!Breached(password) && !Breached(strings.ToLower(password)), key, "is too common and has appeared in a data breach")
+	v.Check(!ContainsPersonalInfo(password, name, email), key, "must not contain your name or email address")
+	v.Check(!SimplePattern(password), key, "must not be a repeated pattern or a simple sequence")
+	v.Check(PasswordEntropy(password) >= minPasswordEntropy, key, "is too easy to guess, use more varied characters")
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}